@@ -0,0 +1,45 @@
+//go:build js && wasm
+
+// Сборка генератора для браузера:
+//
+//	GOOS=js GOARCH=wasm go build -o polyiamond.wasm ./cmd/wasm
+//
+// Вместе с wasm_exec.js из $(go env GOROOT)/lib/wasm программа добавляет
+// в JavaScript функцию generatePatterns(n), которая возвращает массив фигур
+// из n треугольников; каждая фигура - массив координат [x, y, z].
+// При неверном n возвращается null.
+package main
+
+import (
+	"context"
+	"syscall/js"
+
+	"github.com/sergeipershin/triangles/polyiamond"
+)
+
+func generatePatterns(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeNumber {
+		return nil
+	}
+	numTriangles := args[0].Int()
+	if numTriangles < polyiamond.MinNumTriangles || numTriangles > polyiamond.MaxNumTriangles {
+		return nil
+	}
+	patterns := polyiamond.GenerateRange(context.Background(), numTriangles, numTriangles, polyiamond.NewPattern(), false, false, nil, 1, "", nil, nil)[0].Patterns()
+	result := make([]any, len(patterns))
+	for i := 0; i < len(patterns); i++ {
+		triangles := patterns[i].Triangles()
+		coords := make([]any, len(triangles))
+		for j := 0; j < len(triangles); j++ {
+			x, y, z := triangles[j].Coords()
+			coords[j] = []any{x, y, z}
+		}
+		result[i] = coords
+	}
+	return result
+}
+
+func main() {
+	js.Global().Set("generatePatterns", js.FuncOf(generatePatterns))
+	select {}
+}
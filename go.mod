@@ -1,10 +1,13 @@
-module main
+module github.com/sergeipershin/triangles
 
 go 1.24.6
 
 require github.com/fogleman/gg v1.3.0 // direct
 
 require (
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	golang.org/x/image v0.30.0 // indirect
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	golang.org/x/image v0.30.0
+	golang.org/x/term v0.34.0
 )
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,767 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/sergeipershin/triangles/polyiamond"
+)
+
+// коды выхода: ошибка и прерывание по Ctrl+C, как принято в оболочках
+const exitError = 1
+const exitInterrupted = 130
+
+func main() {
+	os.Exit(run())
+}
+
+// run возвращает код выхода; отложенные вызовы, например запись
+// профилей, выполняются до выхода из программы и могут его изменить
+func run() (code int) {
+	var input string
+	var failed bool
+	var minTriangles, maxTriangles int
+	var err error
+	var opts polyiamond.RenderOptions
+
+	// "serve" в начале командной строки - то же, что -serve, адрес можно
+	// указать после флагов, по умолчанию :8080
+	serveCommand := len(os.Args) > 1 && os.Args[1] == "serve"
+	if serveCommand {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	flag.IntVar(&opts.Size, "size", 0, "размер изображения в пикселях (0 - масштаб по умолчанию)")
+	flag.Float64Var(&opts.Padding, "padding", 0, "отступ по краям изображения в пикселях (0 - по умолчанию)")
+	flag.IntVar(&opts.MinSize, "min-size", 0, "минимальный размер изображения в пикселях")
+	flag.Float64Var(&opts.Scale, "scale", 0, "длина стороны треугольника в пикселях, если не задан -size (0 - 200)")
+	flag.Float64Var(&opts.GridWidth, "grid-width", 0, "толщина линий сетки (0 - 0.3)")
+	flag.Float64Var(&opts.EdgeWidth, "edge-width", 0, "толщина внутренних сторон фигуры (0 - 2)")
+	flag.Float64Var(&opts.BoundaryWidth, "boundary-width", 0, "толщина контура фигуры (0 - 5)")
+	flag.BoolVar(&opts.ShowIndex, "index", false, "подписывать номера треугольников")
+	flag.BoolVar(&opts.Heatmap, "heatmap", false, "закрашивать треугольники по удалённости от центра")
+	flag.BoolVar(&opts.OutlineOnly, "outline", false, "рисовать только фигуру, без сетки и осей")
+	flag.BoolVar(&opts.Transparent, "transparent", false, "прозрачный фон")
+	flag.BoolVar(&opts.Fill, "fill", false, "закрашивать треугольники по направлению")
+	flag.StringVar(&opts.UpColor, "up-color", "#9ecae1", "цвет \"верхних\" треугольников")
+	flag.StringVar(&opts.DownColor, "down-color", "#fdd0a2", "цвет \"нижних\" треугольников")
+	flag.StringVar(&opts.FillColor, "fill-color", "", "закрасить всю фигуру одним цветом, например #c7e9c0")
+	flag.BoolVar(&opts.CellColors, "cell-colors", false, "закрашивать каждый треугольник своим цветом")
+	flag.StringVar(&opts.Theme, "theme", "light", "тема оформления: light, dark или print")
+	flag.StringVar(&opts.BackgroundColor, "bg-color", "", "цвет фона вместо цвета темы, например #000000")
+	flag.StringVar(&opts.GridColor, "grid-color", "", "цвет линий сетки вместо цвета темы")
+	flag.StringVar(&opts.AxesColor, "axes-color", "", "цвет осей и подписей вместо цвета темы")
+	flag.StringVar(&opts.EdgeColor, "edge-color", "", "цвет сторон фигуры вместо цвета темы")
+	flag.BoolVar(&opts.SharpCorners, "sharp", false, "острые углы линий вместо скруглённых")
+	flag.BoolVar(&opts.HexClip, "hex", false, "обрезать сетку по шестиугольнику вокруг фигуры")
+	flag.BoolVar(&opts.Legend, "legend", false, "подписать оси и показать масштаб")
+	flag.BoolVar(&opts.NoGrid, "no-grid", false, "не рисовать сетку")
+	flag.BoolVar(&opts.NoAxes, "no-axes", false, "не рисовать оси")
+	flag.BoolVar(&opts.GridUnderShape, "grid-under", false, "рисовать сетку только под фигурой")
+	saveCSV := flag.Bool("csv", false, "сохранить координаты фигур в patterns.csv")
+	montageColumns := flag.Int("montage", 0, "сохранить все фигуры одного размера на одном листе montage.png с заданным числом столбцов")
+	uniform := flag.Bool("uniform", false, "рисовать все фигуры запуска в одном масштабе на холстах одного размера по самой большой фигуре")
+	saveDXF := flag.Bool("dxf", false, "сохранить контуры фигур в DXF для лазерной резки")
+	graphFormat := flag.String("graph", "", "сохранить граф смежности треугольников каждой фигуры: dot (Graphviz) или graphml")
+	edgeMM := flag.Float64("edge-mm", 20, "длина стороны треугольника в миллиметрах для -dxf")
+	savePDF := flag.Bool("pdf", false, "сохранить каталог фигур с подписями для печати в catalog.pdf")
+	saveSummary := flag.Bool("summary", false, "сохранить периметр, размеры и симметрию фигур в summary.csv")
+	saveText := flag.Bool("txt", false, "сохранить координаты фигур в patterns.txt, по фигуре в строке")
+	saveJSON := flag.Bool("json", false, "сохранить координаты и свойства фигур в patterns.json")
+	flag.BoolVar(&opts.Sidecar, "sidecar", false, "сохранить рядом с изображением каждой фигуры файл .json с её координатами и свойствами")
+	browse := flag.Bool("browse", false, "просмотреть фигуры в терминале вместо сохранения изображений")
+	favoritesPath := flag.String("favorites", "favorites.json", "файл, в который при просмотре сохраняются отмеченные фигуры")
+	printASCII := flag.Bool("ascii", false, "напечатать фигуры символами / и \\ в терминале")
+	saveGIF := flag.Bool("gif", false, "сохранить анимацию построения каждой фигуры в GIF")
+	renderIndices := flag.String("render-indices", "", "рисовать только фигуры с этими номерами, например 3,7,100-120")
+	onlyConvex := flag.Bool("convex", false, "оставить только выпуклые фигуры")
+	minCompactness := flag.Float64("min-compactness", 0, "оставить только фигуры с компактностью 4π·площадь/периметр² не меньше заданной, например 0.7")
+	sortBy := flag.String("sort", "", "порядок фигур: compactness - от самых компактных к самым вытянутым")
+	renderLimit := flag.Int("limit", 0, "рисовать не больше стольких первых фигур каждого размера (0 - все)")
+	saveManifest := flag.Bool("manifest", false, "сохранить описание изображений в manifest.json")
+	serveAddr := flag.String("serve", "", "запустить HTTP-сервер по адресу, например :8080")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "число потоков для генерации и сохранения изображений")
+	flag.IntVar(jobs, "workers", runtime.NumCPU(), "то же, что -jobs")
+	upTo := flag.Int("upto", 0, "перебрать все размеры от 1 до заданного за один проход, продолжая фигуры предыдущего размера")
+	sampleCount := flag.Int("sample", 0, "вместо полного перебора выбрать столько случайных фигур каждого размера, равновероятных среди всех фигур")
+	sampleSeed := flag.Uint64("sample-seed", 1, "начальное значение генератора для -sample: при одинаковом значении выбираются одни и те же фигуры")
+	numArg := flag.String("n", "", "количество треугольников или диапазон, например 8 или 4-10; без него спрашивается при запуске")
+	flag.BoolVar(&opts.SymmetryInName, "sym-names", false, "добавлять группу симметрии к именам файлов, например 3_D1.png")
+	flag.StringVar(&opts.NameTemplate, "name", "", "шаблон имени файла фигуры, например \"{n}-{index}-{symmetry}.png\"; подстановки {n}, {index}, {symmetry}, {perimeter}, {code}, {ext}")
+	flag.StringVar(&opts.Format, "format", "png", "формат изображений: png или svg")
+	placements := flag.Bool("placements", false, "сохранять все положения фигур, без отождествления поворотов, отражений и сдвигов")
+	repTileOrder := flag.Int("reptile", 0, "найти rep-tiles: фигуры, которые k своими копиями покрывают себя, увеличенную в √k раз, для k = 4, 9, ... не больше заданного")
+	verify := flag.Bool("verify", false, "проверить число фигур по лемме Бернсайда независимым подсчётом фиксированных фигур")
+	checkOEIS := flag.Bool("oeis", false, "сверить число фигур с известными значениями A000577 из OEIS")
+	estimate := flag.Bool("estimate", false, "оценить число фигур, время и память, не генерируя их")
+	quiet := flag.Bool("quiet", false, "не выводить ход генерации и статистику")
+	stream := flag.Bool("stream", false, "сохранять изображения по мере нахождения фигур")
+	mergePaths := flag.String("merge", "", "объединить фигуры из файлов через запятую без повторов и сохранить в -out (по умолчанию merged.json)")
+	loadPath := flag.String("load", "", "нарисовать фигуры из сохранённого файла .json, .csv или текстового (фигура в строке)")
+	drawCoords := flag.String("draw", "", "нарисовать одну фигуру по координатам, например \"0,1,0 0,0,-1\"")
+	compare := flag.String("compare", "", "сравнить фигуры, заданные координатами и разделённые \"|\"")
+	outPath := flag.String("out", "", "путь к выходному файлу или каталогу для результатов")
+	seedCoords := flag.String("seed", "", "начальные треугольники, например \"0,1,0 1,0,0\"")
+	seedDown := flag.Bool("down", false, "начинать построение с \"нижнего\" треугольника")
+	checkpointDir := flag.String("checkpoint", "", "каталог для контрольных точек: прерванная генерация продолжится с последней точки")
+	coordinateAddr := flag.String("coordinate", "", "раздавать перебор рабочим по адресу, например :9000; фигуры сохраняются краткими записями в patterns.txt")
+	workerURL := flag.String("worker", "", "перебирать части перебора, полученные от координатора, например http://host:9000")
+	splitDepth := flag.Int("split-depth", 8, "длина начал фигур, по которым -coordinate делит перебор на части")
+	excludePath := flag.String("exclude", "", "JSON с уже известными фигурами, которые не нужно сохранять")
+	gridName := flag.String("grid", "triangle", "решётка: triangle, square (полимино) или hex (полигексы)")
+	tileRegion := flag.String("tile", "", "покрыть область фигурами, например hexagon:2, triangle:4 или parallelogram:2x3")
+	tilePieces := flag.String("pieces", "", "фигуры для -tile и -groups: число треугольников или файл с фигурами")
+	tileReuse := flag.Bool("reuse", false, "разрешить использовать фигуру в покрытии несколько раз")
+	groupRegions := flag.String("groups", "", "найти наборы фигур из -pieces, которые вместе покрывают области: standard - все шестиугольники, треугольники и параллелограммы подходящей площади, или список, например hexagon:2,parallelogram:3x4")
+	kitSpec := flag.String("kit", "", "нарисовать набор фигур разных размеров в одном масштабе, например 4x4,6x6,2x8 - число фигур x число треугольников")
+	tileLimit := flag.Int("solutions", 10, "наибольшее число сохраняемых покрытий (0 - все)")
+	cpuProfile := flag.String("cpuprofile", "", "записать профиль процессора в файл")
+	memProfile := flag.String("memprofile", "", "записать профиль памяти в файл по окончании работы")
+	flag.Parse()
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		err = pprof.StartCPUProfile(f)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer pprof.StopCPUProfile()
+	}
+	if *memProfile != "" {
+		defer func() {
+			f, err := os.Create(*memProfile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				code = exitError
+				return
+			}
+			defer f.Close()
+			runtime.GC()
+			err = pprof.WriteHeapProfile(f)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				code = exitError
+			}
+		}()
+	}
+
+	if opts.Format != "png" && opts.Format != "svg" {
+		fmt.Fprintf(os.Stderr, "Неизвестный формат %q\n", opts.Format)
+		return exitError
+	}
+	var indices []int
+	if *renderIndices != "" {
+		indices, err = polyiamond.ParseIndices(*renderIndices)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+	if *renderLimit < 0 {
+		fmt.Fprintln(os.Stderr, "Значение -limit не может быть отрицательным")
+		return exitError
+	}
+	if *sortBy != "" && *sortBy != "compactness" {
+		fmt.Fprintf(os.Stderr, "Неизвестный порядок %q, допустим compactness\n", *sortBy)
+		return exitError
+	}
+	if *graphFormat != "" && *graphFormat != "dot" && *graphFormat != "graphml" {
+		fmt.Fprintf(os.Stderr, "Неизвестный формат графа %q, допустимы dot и graphml\n", *graphFormat)
+		return exitError
+	}
+	filtering := *onlyConvex || *minCompactness > 0 || *sortBy != ""
+	err = opts.CheckTheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if opts.NameTemplate != "" {
+		err = polyiamond.ValidateNameTemplate(opts.NameTemplate)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+
+	if serveCommand && *serveAddr == "" {
+		*serveAddr = ":8080"
+		if flag.NArg() > 0 {
+			*serveAddr = flag.Arg(0)
+		}
+	}
+	if *serveAddr != "" {
+		err = polyiamond.Serve(*serveAddr, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return 0
+	}
+
+	if *workerURL != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		processed, err := polyiamond.Work(ctx, *workerURL, *jobs, os.Stderr)
+		interrupted := ctx.Err() != nil
+		stop()
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Перебрано частей: %d\n", processed)
+		}
+		switch {
+		case interrupted:
+			return exitInterrupted
+		case err != nil:
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return 0
+	}
+	if *mergePaths != "" {
+		path := *outPath
+		if path == "" {
+			path = "merged.json"
+		}
+		saved, duplicates, err := polyiamond.MergeFiles(strings.Split(*mergePaths, ","), path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Сохранено фигур: %d, отброшено повторов: %d\n", saved, duplicates)
+		}
+		return 0
+	}
+	if *loadPath != "" && *browse {
+		err = polyiamond.BrowseFile(*loadPath, os.Stdin, os.Stdout, *favoritesPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return 0
+	}
+	if *loadPath != "" {
+		err = polyiamond.RenderLoaded(*loadPath, *outPath, opts, *jobs, os.Stderr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return 0
+	}
+
+	if *drawCoords != "" {
+		err = polyiamond.DrawSingle(*drawCoords, *outPath, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return 0
+	}
+
+	if *compare != "" {
+		err = polyiamond.SaveComparison(*compare, *outPath, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return 0
+	}
+
+	if *tileRegion != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		found, err := polyiamond.SaveTilings(ctx, *tileRegion, *tilePieces, *tileReuse, *tileLimit, *outPath, *jobs, opts)
+		interrupted := ctx.Err() != nil
+		stop()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Найдено покрытий: %d\n", found)
+		}
+		if interrupted {
+			return exitInterrupted
+		}
+		return 0
+	}
+
+	if *kitSpec != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		saved, err := polyiamond.SaveKit(ctx, *kitSpec, *outPath, opts, *jobs)
+		interrupted := ctx.Err() != nil
+		stop()
+		if interrupted {
+			return exitInterrupted
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Сохранено фигур набора: %d\n", saved)
+		}
+		return 0
+	}
+
+	if *groupRegions != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if *tilePieces == "" {
+			fmt.Fprintln(os.Stderr, "Для -groups нужно указать фигуры в -pieces")
+			return exitError
+		}
+		pieces, err := polyiamond.LoadPieces(ctx, *tilePieces, *jobs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		if len(pieces) == 0 {
+			fmt.Fprintln(os.Stderr, "Нет фигур для покрытия")
+			return exitError
+		}
+		regions := polyiamond.GroupRegions(*groupRegions, pieces)
+		for i := 0; i < len(regions) && ctx.Err() == nil; i++ {
+			groups, err := polyiamond.SaveCompatibleGroups(ctx, regions[i], pieces, *outPath, *jobs, opts)
+			for j := 0; j < len(groups); j++ {
+				names := make([]string, len(groups[j].Pieces))
+				for k := 0; k < len(names); k++ {
+					names[k] = fmt.Sprintf("%d (%s)", groups[j].Pieces[k], groups[j].Codes[k])
+				}
+				fmt.Printf("%s: %s\n", regions[i], strings.Join(names, ", "))
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitError
+			}
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "%s: наборов %d\n", regions[i], len(groups))
+			}
+		}
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return 0
+	}
+
+	if *gridName != "triangle" {
+		if *sampleCount != 0 {
+			fmt.Fprintln(os.Stderr, "-sample работает только на треугольной решётке")
+			return exitError
+		}
+		grid, err := polyiamond.GridByName(*gridName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		minCells, maxCells, err := polyiamond.ParseRange(*numArg)
+		if err != nil || minCells < 1 || minCells > maxCells {
+			fmt.Fprintln(os.Stderr, "Неправильное значение -n")
+			return exitError
+		}
+		outDir := *outPath
+		if outDir == "" {
+			outDir = "."
+		}
+		err = polyiamond.CheckWritable(outDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		for n := minCells; n <= maxCells && ctx.Err() == nil; n++ {
+			patterns := polyiamond.GenerateGrid(ctx, grid, n)
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "%d клеток: %d фигур\n", n, len(patterns))
+			}
+			err = polyiamond.SaveGridPatterns(filepath.Join(outDir, fmt.Sprint(n)), grid, patterns, opts, *jobs)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitError
+			}
+		}
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return 0
+	}
+
+	seed, err := polyiamond.ParsePattern(*seedCoords)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if *seedDown && seed.Len() == 0 {
+		seed, _ = polyiamond.ParsePattern("0,-1,0")
+	}
+	var known map[string]bool
+	if *excludePath != "" {
+		known, err = polyiamond.LoadKnown(*excludePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	}
+	if !seed.IsConnected() {
+		fmt.Fprintln(os.Stderr, "Начальные треугольники должны быть связаны сторонами")
+		return exitError
+	}
+
+	if *upTo != 0 {
+		if *upTo < polyiamond.MinNumTriangles || *upTo > polyiamond.MaxNumTriangles {
+			fmt.Fprintf(os.Stderr, "Значение -upto должно быть от %d до %d\n", polyiamond.MinNumTriangles, polyiamond.MaxNumTriangles)
+			return exitError
+		}
+		if seed.Len() > 0 || *placements || known != nil || *stream || *checkpointDir != "" {
+			fmt.Fprintln(os.Stderr, "-upto нельзя сочетать с -seed, -down, -placements, -exclude, -stream и -checkpoint")
+			return exitError
+		}
+		minTriangles, maxTriangles = 1, *upTo
+	} else {
+		input = *numArg
+		if input == "" {
+			fmt.Printf("Введите количество треугольников (от %d) или диапазон, например 4-10: ", polyiamond.MinNumTriangles)
+			fmt.Scanln(&input)
+		}
+		minTriangles, maxTriangles, err = polyiamond.ParseRange(input)
+		if err != nil || minTriangles < max(polyiamond.MinNumTriangles, seed.Len()) || minTriangles > maxTriangles {
+			fmt.Fprintln(os.Stderr, "Неправильное значение")
+			return exitError
+		}
+	}
+
+	if *estimate {
+		estimates, err := polyiamond.EstimateRun(minTriangles, maxTriangles)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		for i := 0; i < len(estimates); i++ {
+			fmt.Println(estimates[i])
+		}
+		return 0
+	}
+
+	outDir := *outPath
+	if outDir == "" {
+		outDir = "."
+	}
+	// недоступный каталог обнаруживается до генерации, а не после неё
+	err = polyiamond.CheckWritable(outDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	sampling := *sampleCount != 0
+	if sampling {
+		if *sampleCount < 0 {
+			fmt.Fprintln(os.Stderr, "Значение -sample должно быть положительным")
+			return exitError
+		}
+		if seed.Len() > 0 || *placements || known != nil || *stream || *upTo != 0 || *checkpointDir != "" || *coordinateAddr != "" || *checkOEIS || *verify {
+			fmt.Fprintln(os.Stderr, "-sample нельзя сочетать с -seed, -down, -placements, -exclude, -stream, -upto, -checkpoint, -coordinate, -oeis и -verify")
+			return exitError
+		}
+	}
+	if maxTriangles > polyiamond.MaxNumTriangles && !*stream && *coordinateAddr == "" && !sampling {
+		fmt.Fprintf(os.Stderr, "Больше %d треугольников: фигуры записываются на диск по мере нахождения\n", polyiamond.MaxNumTriangles)
+		*stream = true
+	}
+	if *stream && (filtering || *uniform) {
+		fmt.Fprintln(os.Stderr, "-convex, -min-compactness, -sort и -uniform нельзя сочетать с -stream: фигуры сохраняются до окончания перебора")
+		return exitError
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if *stream {
+		defer stop()
+		for n := minTriangles; n <= maxTriangles && ctx.Err() == nil; n++ {
+			err = polyiamond.StreamPatterns(ctx, filepath.Join(outDir, fmt.Sprint(n)), n, seed, known, opts, *jobs)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitError
+			}
+		}
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		return 0
+	}
+	var onProgress func(numTriangles, nodes, accepted int)
+	if !*quiet {
+		var sizeStart time.Time
+		var startNodes int
+		var totalNodes float64
+		currentSize := 0
+		// оставшееся время считается по скорости перебора с первого отчёта;
+		// оценка числа вариантов есть только для перебора без ограничений
+		withETA := seed.Len() == 0 && !*placements && known == nil && *upTo == 0 && *coordinateAddr == "" && !sampling
+		onProgress = func(numTriangles, nodes, accepted int) {
+			if numTriangles != currentSize {
+				currentSize = numTriangles
+				sizeStart = time.Now()
+				startNodes = nodes
+				if withETA {
+					totalNodes = polyiamond.EstimateNodes(numTriangles)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "\r%d: просмотрено вариантов %d, найдено фигур %d", numTriangles, nodes, accepted)
+			if withETA && nodes > startNodes && float64(nodes) < totalNodes {
+				remaining := time.Duration(float64(time.Since(sizeStart)) * (totalNodes - float64(nodes)) / float64(nodes-startNodes))
+				fmt.Fprintf(os.Stderr, ", осталось около %v   ", remaining.Round(time.Second))
+			}
+		}
+	}
+	if *coordinateAddr != "" {
+		if seed.Len() > 0 || *placements || known != nil || *stream || *upTo != 0 || filtering {
+			fmt.Fprintln(os.Stderr, "-coordinate нельзя сочетать с -seed, -down, -placements, -exclude, -stream, -upto и отбором фигур")
+			return exitError
+		}
+		if *splitDepth < 1 {
+			fmt.Fprintln(os.Stderr, "Значение -split-depth должно быть положительным")
+			return exitError
+		}
+		counts, err := polyiamond.Coordinate(ctx, *coordinateAddr, minTriangles, maxTriangles, *splitDepth, outDir, *checkpointDir, os.Stderr, onProgress)
+		// прерывание после окончания перебора результат не портит
+		interrupted := err != nil && ctx.Err() != nil
+		stop()
+		fmt.Fprintln(os.Stderr)
+		if err != nil && !interrupted {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		if !*quiet || *checkOEIS {
+			matched, err := polyiamond.WriteCounts(os.Stderr, counts, minTriangles, *checkOEIS && !interrupted)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			} else if !matched {
+				fmt.Fprintln(os.Stderr, "Число фигур не совпадает с OEIS")
+				return exitError
+			}
+		}
+		if interrupted {
+			return exitInterrupted
+		}
+		return 0
+	}
+	if *placements {
+		fmt.Fprintln(os.Stderr, "Внимание: без учёта симметрии фигур получится во много раз больше")
+	}
+	var collections []*polyiamond.Collection
+	var genErr error
+	switch {
+	case sampling:
+		collections = polyiamond.SampleRange(ctx, minTriangles, maxTriangles, *sampleCount, *sampleSeed, *jobs, onProgress)
+	case *upTo != 0:
+		collections, genErr = polyiamond.GenerateUpTo(ctx, maxTriangles, *saveGIF, *jobs, onProgress)
+	default:
+		collections = polyiamond.GenerateRange(ctx, minTriangles, maxTriangles, seed, *placements, *saveGIF, known, *jobs, *checkpointDir, os.Stderr, onProgress)
+	}
+	interrupted := ctx.Err() != nil
+	stop()
+	fmt.Fprintln(os.Stderr)
+	if genErr != nil {
+		fmt.Fprintln(os.Stderr, genErr)
+		return exitError
+	}
+	// отбор и порядок касаются только сохраняемых фигур, сверка числа
+	// фигур идёт по полным коллекциям
+	shown := collections
+	if filtering {
+		shown = make([]*polyiamond.Collection, len(collections))
+		for i := 0; i < len(collections); i++ {
+			shown[i] = collections[i].Filtered(func(p *polyiamond.Pattern) bool {
+				return (!*onlyConvex || p.IsConvex()) && p.Compactness() >= *minCompactness
+			})
+			if *sortBy == "compactness" {
+				shown[i].SortByCompactness()
+			}
+		}
+	}
+	if *browse {
+		err = polyiamond.Browse(os.Stdin, os.Stdout, shown, *favoritesPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return 0
+	}
+	if interrupted {
+		fmt.Fprintln(os.Stderr, "Генерация прервана, сохраняются найденные фигуры")
+	}
+	if *uniform {
+		opts.ViewRadius = max(opts.ViewRadius, polyiamond.CommonViewRadius(shown))
+	}
+	for i := 0; i < len(shown); i++ {
+		dir := filepath.Join(outDir, fmt.Sprint(minTriangles+i))
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "%d треугольников:\n%s", minTriangles+i, shown[i].Summarize())
+		}
+		selected := polyiamond.SelectIndices(len(shown[i].Patterns()), indices, *renderLimit)
+		filenames, errs := polyiamond.SavePatterns(dir, shown[i], opts, *jobs, selected)
+		for j := 0; j < len(errs); j++ {
+			fmt.Fprintln(os.Stderr, errs[j])
+			failed = true
+		}
+		if *saveManifest {
+			err = shown[i].SaveManifest(filepath.Join(dir, "manifest.json"), filenames)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *printASCII {
+			err = shown[i].WriteASCII(os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *saveGIF {
+			err = shown[i].SaveGrowthGIFs(dir, seed, opts, *jobs)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *saveJSON {
+			err = shown[i].SaveJSON(filepath.Join(dir, "patterns.json"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *montageColumns > 0 {
+			err = shown[i].SaveMontage(filepath.Join(dir, "montage.png"), opts, *montageColumns)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *saveDXF {
+			err = shown[i].SaveDXF(dir, opts, *edgeMM)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *graphFormat != "" {
+			err = shown[i].SaveGraphs(dir, opts, *graphFormat)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *savePDF {
+			err = shown[i].SaveCatalogPDF(filepath.Join(dir, "catalog.pdf"), opts)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *saveSummary {
+			err = shown[i].SaveSummary(filepath.Join(dir, "summary.csv"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *saveText {
+			err = shown[i].SaveText(filepath.Join(dir, "patterns.txt"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+		if *saveCSV {
+			err = shown[i].SaveCSV(filepath.Join(dir, "patterns.csv"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+		}
+	}
+	// при -sample число фигур задано заранее
+	if !sampling && (!*quiet || *checkOEIS) {
+		// значения OEIS относятся только к полному перебору без ограничений
+		check := *checkOEIS && !interrupted && seed.Len() == 0 && !*placements && known == nil
+		if *checkOEIS && !check {
+			fmt.Fprintln(os.Stderr, "Сверка с OEIS возможна только для полного перебора без -seed, -placements и -exclude")
+		}
+		matched, err := polyiamond.WriteCountTable(os.Stderr, collections, minTriangles, check)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else if !matched {
+			fmt.Fprintln(os.Stderr, "Число фигур не совпадает с OEIS")
+			failed = true
+		}
+	}
+	if *verify {
+		if interrupted || seed.Len() > 0 || *placements || known != nil {
+			fmt.Fprintln(os.Stderr, "Проверка по лемме Бернсайда возможна только для полного перебора без -seed, -placements и -exclude")
+		} else {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			for i := 0; i < len(collections) && ctx.Err() == nil; i++ {
+				n := minTriangles + i
+				count, fixed, err := polyiamond.BurnsideCount(ctx, n)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					failed = true
+					continue
+				}
+				found := len(collections[i].Patterns())
+				fmt.Fprintf(os.Stderr, "%d: найдено фигур %d, по лемме Бернсайда %d (фиксированных %d)", n, found, count, fixed)
+				if found != count {
+					fmt.Fprint(os.Stderr, " - не совпадает")
+					failed = true
+				}
+				fmt.Fprintln(os.Stderr)
+			}
+			interrupted = ctx.Err() != nil
+			stop()
+		}
+	}
+	if *repTileOrder > 0 && !interrupted {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		for i := 0; i < len(shown) && ctx.Err() == nil; i++ {
+			n := minTriangles + i
+			reptiles, err := polyiamond.FindRepTiles(ctx, shown[i], *repTileOrder, *jobs)
+			if err != nil {
+				if ctx.Err() == nil {
+					fmt.Fprintln(os.Stderr, err)
+					failed = true
+				}
+				continue
+			}
+			for j := 0; j < len(reptiles); j++ {
+				orders := make([]string, len(reptiles[j].Orders))
+				for k := 0; k < len(orders); k++ {
+					orders[k] = fmt.Sprintf("rep-%d", reptiles[j].Orders[k])
+				}
+				fmt.Printf("%d: фигура %d (%s) - %s\n", n, reptiles[j].Index, reptiles[j].Code, strings.Join(orders, ", "))
+			}
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "%d: rep-tiles %d из %d\n", n, len(reptiles), len(shown[i].Patterns()))
+			}
+		}
+		interrupted = ctx.Err() != nil
+		stop()
+	}
+	switch {
+	case failed:
+		return exitError
+	case interrupted:
+		return exitInterrupted
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package polyiamond
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Для текстового вида сетка повёрнута на 90 градусов по часовой стрелке,
+// чтобы стороны треугольников стали горизонтальными: "верхний" треугольник
+// печатается как /\, "нижний" - как \/. Строка текста соответствует
+// полосе треугольников между двумя соседними горизонтальными линиями.
+// Соседние треугольники не делят символы сторон, иначе промежуток между
+// двумя "верхними" неотличим от "нижнего" треугольника, поэтому каждый
+// занимает своё место шириной asciiCellWidth.
+const asciiCellWidth = 3
+
+func (t *Triangle) asciiPosition() (int, int) {
+	row := t.x
+	if t.isUpward() {
+		row--
+	}
+	return row, t.y - t.z
+}
+
+// WritePatternASCII печатает фигуру символами / и \
+func WritePatternASCII(w io.Writer, p *Pattern) error {
+	var row, col, minRow, maxRow, minCol, maxCol int
+	var sb strings.Builder
+	if p.Len() == 0 {
+		return nil
+	}
+	for i := 0; i < len(p.triangles); i++ {
+		row, col = p.triangles[i].asciiPosition()
+		if i == 0 {
+			minRow, maxRow, minCol, maxCol = row, row, col, col
+		}
+		minRow = min(minRow, row)
+		maxRow = max(maxRow, row)
+		minCol = min(minCol, col)
+		maxCol = max(maxCol, col)
+	}
+	lines := make([][]rune, maxRow-minRow+1)
+	for i := 0; i < len(lines); i++ {
+		lines[i] = []rune(strings.Repeat(" ", (maxCol-minCol)*asciiCellWidth+2))
+	}
+	for i := 0; i < len(p.triangles); i++ {
+		row, col = p.triangles[i].asciiPosition()
+		row -= minRow
+		col = (col - minCol) * asciiCellWidth
+		if p.triangles[i].isUpward() {
+			lines[row][col], lines[row][col+1] = '/', '\\'
+		} else {
+			lines[row][col], lines[row][col+1] = '\\', '/'
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		sb.WriteString(strings.TrimRight(string(lines[i]), " "))
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteASCII печатает все фигуры коллекции, каждую под её номером
+func (pc *Collection) WriteASCII(w io.Writer) error {
+	for i := 0; i < len(pc.patterns); i++ {
+		if _, err := fmt.Fprintf(w, "%d:\n", i); err != nil {
+			return err
+		}
+		if err := WritePatternASCII(w, pc.patterns[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
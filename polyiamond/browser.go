@@ -0,0 +1,165 @@
+package polyiamond
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Просмотр фигур в терминале: по экрану на фигуру, с текстовым видом
+// из WritePatternASCII и свойствами. Терминал переводится в режим без
+// буферизации строк, экран перерисовывается управляющими
+// последовательностями ANSI.
+
+const browserHelp = "←/→ или p/n - листать, Home/End - в начало/конец, f - отметить, e - сохранить отмеченные, q - выход"
+
+type browserEntry struct {
+	p     *Pattern
+	index int
+}
+
+type browser struct {
+	entries    []browserEntry
+	current    int
+	favorites  map[int]bool
+	exportPath string
+	status     string
+}
+
+// Browse показывает фигуры коллекций в терминале in/out; отмеченные фигуры
+// по команде e сохраняются в exportPath в формате JSON
+func Browse(in, out *os.File, collections []*Collection, exportPath string) error {
+	var n int
+	var err error
+	b := &browser{favorites: make(map[int]bool), exportPath: exportPath}
+	for i := 0; i < len(collections); i++ {
+		for j := 0; j < len(collections[i].patterns); j++ {
+			b.entries = append(b.entries, browserEntry{p: collections[i].patterns[j], index: j})
+		}
+	}
+	if len(b.entries) == 0 {
+		return fmt.Errorf("нет фигур для просмотра")
+	}
+	if !term.IsTerminal(int(in.Fd())) {
+		return fmt.Errorf("для просмотра нужен терминал")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	// альтернативный экран не оставляет следов просмотра в истории терминала
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 8)
+	for {
+		err = b.draw(out)
+		if err != nil {
+			return err
+		}
+		n, err = in.Read(buf)
+		if err != nil {
+			return err
+		}
+		if !b.handleKey(string(buf[:n])) {
+			return nil
+		}
+	}
+}
+
+// возвращает false, если просмотр нужно закончить
+func (b *browser) handleKey(key string) bool {
+	b.status = ""
+	switch key {
+	case "q", "Q", "\x03", "\x1b":
+		return false
+	case "n", " ", "j", "\x1b[C", "\x1b[B":
+		b.current = min(b.current+1, len(b.entries)-1)
+	case "p", "k", "\x1b[D", "\x1b[A":
+		b.current = max(b.current-1, 0)
+	case "\x1b[H", "\x1b[1~", "g":
+		b.current = 0
+	case "\x1b[F", "\x1b[4~", "G":
+		b.current = len(b.entries) - 1
+	case "f":
+		if b.favorites[b.current] {
+			delete(b.favorites, b.current)
+		} else {
+			b.favorites[b.current] = true
+		}
+	case "e":
+		b.status = b.export()
+	}
+	return true
+}
+
+// сохраняет отмеченные фигуры в порядке просмотра
+func (b *browser) export() string {
+	var selected []int
+	if len(b.favorites) == 0 {
+		return "нет отмеченных фигур"
+	}
+	for i := range b.favorites {
+		selected = append(selected, i)
+	}
+	sort.Ints(selected)
+	pc := NewCollection()
+	for i := 0; i < len(selected); i++ {
+		pc.patterns = append(pc.patterns, b.entries[selected[i]].p)
+	}
+	err := pc.SaveJSON(b.exportPath)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("отмеченные фигуры (%d) сохранены в %s", len(selected), b.exportPath)
+}
+
+func (b *browser) draw(out io.Writer) error {
+	var sb strings.Builder
+	e := b.entries[b.current]
+	fmt.Fprintf(&sb, "Фигура %d из %d: %d треугольников, номер %d", b.current+1, len(b.entries), e.p.Len(), e.index)
+	if b.favorites[b.current] {
+		sb.WriteString("  ★")
+	}
+	sb.WriteString("\n\n")
+	fmt.Fprintf(&sb, "код: %s\n", e.p.Encode())
+	fmt.Fprintf(&sb, "периметр: %d, симметрия: %s, положений: %d", e.p.Perimeter(), e.p.symmetryGroup(), len(e.p.allVariants()))
+	if e.p.isChiral() {
+		sb.WriteString(", хиральная")
+	}
+	if e.p.hasHoles() {
+		sb.WriteString(", с дырами")
+	}
+	sb.WriteString("\n\n")
+	err := WritePatternASCII(&sb, e.p)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(&sb, "\nотмечено: %d\n%s\n", len(b.favorites), browserHelp)
+	if b.status != "" {
+		sb.WriteString(b.status + "\n")
+	}
+	// в режиме без буферизации перевод строки не возвращает каретку
+	_, err = io.WriteString(out, "\x1b[H\x1b[2J"+strings.ReplaceAll(sb.String(), "\n", "\r\n"))
+	return err
+}
+
+// BrowseFile показывает фигуры из файла в формате, который понимает -load
+func BrowseFile(path string, in, out *os.File, exportPath string) error {
+	pc, err := loadPatterns(path)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(pc.patterns); i++ {
+		err = validatePattern(pc.patterns[i])
+		if err != nil {
+			return fmt.Errorf("фигура %d: %v", i, err)
+		}
+	}
+	return Browse(in, out, []*Collection{pc}, exportPath)
+}
@@ -0,0 +1,34 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+)
+
+// порядок группы симметрий треугольной сетки: 6 поворотов и 6 отражений
+const symmetryGroupOrder = 12
+
+// BurnsideCount считает фигуры из numTriangles треугольников независимо
+// от отбора канонических форм: по лемме Бернсайда число фигур равно
+// среднему по группе симметрий числу фиксированных фигур, которые
+// симметрия переводит в их сдвиг. Возвращает также число фиксированных фигур.
+func BurnsideCount(ctx context.Context, numTriangles int) (int, int, error) {
+	var rotations, reflections int
+	fixed, sum := 0, 0
+	pc := NewCollection()
+	pc.ctx = ctx
+	pc.onFixed = func(p *Pattern) {
+		rotations, reflections = p.symmetryCounts()
+		fixed++
+		sum += rotations + reflections
+	}
+	pc.generateRedelmeier(numTriangles)
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	if sum%symmetryGroupOrder != 0 {
+		return 0, fixed, fmt.Errorf("%d: сумма неподвижных фигур %d не делится на %d, симметрии сетки вычисляются неверно",
+			numTriangles, sum, symmetryGroupOrder)
+	}
+	return sum / symmetryGroupOrder, fixed, nil
+}
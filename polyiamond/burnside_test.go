@@ -0,0 +1,23 @@
+package polyiamond
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBurnsideCount(t *testing.T) {
+	// фиксированные полиамонды, A001420
+	fixedCounts := []int{2, 3, 6, 14, 36, 94, 250, 675}
+	for n := 1; n <= len(fixedCounts); n++ {
+		count, fixed, err := BurnsideCount(context.Background(), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(generated(n)) || count != freeCounts[n-1] {
+			t.Errorf("%d треугольников: по лемме Бернсайда %d, перебором %d", n, count, len(generated(n)))
+		}
+		if fixed != fixedCounts[n-1] {
+			t.Errorf("%d треугольников: фиксированных фигур %d, ожидалось %d", n, fixed, fixedCounts[n-1])
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package polyiamond
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const checkpointInterval = time.Minute
+
+// версия формата контрольной точки; увеличивается при любом изменении
+// перебора, меняющем номера заготовок или найденные в них фигуры
+const checkpointVersion = 2
+
+// Контрольная точка параллельной генерации: номера полностью перебранных
+// заготовок и найденные в них фигуры. Заготовки перечисляются в одном
+// и том же порядке, поэтому при возобновлении готовые пропускаются,
+// а остальные перебираются заново; результат совпадает с непрерывным запуском.
+type checkpoint struct {
+	Version      int    `json:"version"`
+	NumTriangles int    `json:"num_triangles"`
+	Seed         string `json:"seed"`
+	Placements   bool   `json:"placements"`
+	RecordSteps  bool   `json:"record_steps"`
+	// хэш канонических форм исключённых фигур
+	Known   string            `json:"known"`
+	Done    []int             `json:"done"`
+	Entries []checkpointEntry `json:"entries"`
+}
+
+type checkpointEntry struct {
+	Task      int      `json:"task"`
+	Seq       int      `json:"seq"`
+	Triangles [][3]int `json:"triangles"`
+	Steps     [][2]int `json:"steps,omitempty"`
+	Root      [3]int   `json:"root"`
+}
+
+// хэш набора известных фигур, не зависящий от порядка; пусто без них
+func knownHash(known map[string]bool) string {
+	if len(known) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(known))
+	for key := range known {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for i := 0; i < len(keys); i++ {
+		h.Write([]byte(keys[i]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checkpointPath(dir string, numTriangles int) string {
+	return filepath.Join(dir, fmt.Sprintf("checkpoint_%d.json", numTriangles))
+}
+
+func newCheckpointEntry(e *sharedEntry) checkpointEntry {
+	ce := checkpointEntry{
+		Task:      e.task,
+		Seq:       e.seq,
+		Triangles: make([][3]int, len(e.p.triangles)),
+	}
+	for i := 0; i < len(e.p.triangles); i++ {
+		ce.Triangles[i] = [3]int{e.p.triangles[i].x, e.p.triangles[i].y, e.p.triangles[i].z}
+	}
+	for i := 0; i < len(e.p.buildSteps); i++ {
+		ce.Steps = append(ce.Steps, [2]int{e.p.buildSteps[i].index, e.p.buildSteps[i].axis})
+	}
+	ce.Root = [3]int{e.p.buildRoot.x, e.p.buildRoot.y, e.p.buildRoot.z}
+	return ce
+}
+
+func (ce checkpointEntry) toSharedEntry() (*sharedEntry, error) {
+	p := NewPattern()
+	for i := 0; i < len(ce.Triangles); i++ {
+		t, err := NewTriangle(ce.Triangles[i][0], ce.Triangles[i][1], ce.Triangles[i][2])
+		if err != nil {
+			return nil, err
+		}
+		p.addTriangle(t)
+	}
+	for i := 0; i < len(ce.Steps); i++ {
+		p.buildSteps = append(p.buildSteps, buildStep{index: ce.Steps[i][0], axis: ce.Steps[i][1]})
+	}
+	p.buildRoot = Triangle{ce.Root[0], ce.Root[1], ce.Root[2]}
+	p.normalizeOrder()
+	p.buildIndex()
+	return &sharedEntry{task: ce.Task, seq: ce.Seq, p: p}, nil
+}
+
+// сохраняет состояние: в файл попадают только фигуры готовых заготовок,
+// незаконченные будут перебраны заново
+func (pc *Collection) saveCheckpoint(shared *sharedSet, done map[int]bool) error {
+	cp := checkpoint{
+		Version:      checkpointVersion,
+		NumTriangles: pc.checkpointSize,
+		Seed:         pc.checkpointSeed,
+		Placements:   pc.placements,
+		RecordSteps:  pc.recordSteps,
+		Known:        knownHash(pc.known),
+	}
+	shared.mu.Lock()
+	for task := range done {
+		cp.Done = append(cp.Done, task)
+	}
+	for _, e := range shared.entries {
+		if done[e.task] {
+			cp.Entries = append(cp.Entries, newCheckpointEntry(e))
+		}
+	}
+	shared.mu.Unlock()
+	sort.Ints(cp.Done)
+	sort.Slice(cp.Entries, func(i, j int) bool {
+		if cp.Entries[i].Task != cp.Entries[j].Task {
+			return cp.Entries[i].Task < cp.Entries[j].Task
+		}
+		return cp.Entries[i].Seq < cp.Entries[j].Seq
+	})
+
+	return writeCheckpoint(pc.checkpointDir, cp)
+}
+
+func writeCheckpoint(dir string, cp checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	path := checkpointPath(dir, cp.NumTriangles)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	// запись через временный файл, чтобы прерванное сохранение
+	// не испортило предыдущую точку
+	err = os.WriteFile(path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// nil без ошибки, если контрольной точки нет
+func readCheckpoint(dir string, numTriangles int) (*checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(checkpointPath(dir, numTriangles))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &cp)
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// загружает контрольную точку того же запуска; точка другого запуска
+// или её отсутствие означают перебор с начала
+func (pc *Collection) loadCheckpoint(shared *sharedSet, done map[int]bool) error {
+	var e *sharedEntry
+	cp, err := readCheckpoint(pc.checkpointDir, pc.checkpointSize)
+	if err != nil || cp == nil {
+		return err
+	}
+	if cp.Version != checkpointVersion {
+		return fmt.Errorf("контрольная точка записана другой версией программы")
+	}
+	if cp.NumTriangles != pc.checkpointSize || cp.Seed != pc.checkpointSeed || cp.Placements != pc.placements ||
+		cp.RecordSteps != pc.recordSteps || cp.Known != knownHash(pc.known) {
+		return fmt.Errorf("контрольная точка относится к другому запуску")
+	}
+	for i := 0; i < len(cp.Done); i++ {
+		done[cp.Done[i]] = true
+	}
+	logf(pc.log, "%d: продолжение с контрольной точки, готово заготовок %d\n", cp.NumTriangles, len(cp.Done))
+	for i := 0; i < len(cp.Entries); i++ {
+		e, err = cp.Entries[i].toSharedEntry()
+		if err != nil {
+			return err
+		}
+		if pc.placements {
+			e.p.validateHash()
+			shared.entries[e.p.patternHash] = e
+		} else {
+			shared.entries[e.p.getCanonical()] = e
+		}
+	}
+	return nil
+}
+
+func (pc *Collection) removeCheckpoint() error {
+	err := os.Remove(checkpointPath(pc.checkpointDir, pc.checkpointSize))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package polyiamond
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func keys(pc *Collection) []string {
+	keys := make([]string, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		keys[i] = pc.patterns[i].Key()
+	}
+	return keys
+}
+
+// перебор, прерванный на середине и продолженный с контрольной точки,
+// находит те же фигуры, что и непрерывный
+func TestCheckpointResume(t *testing.T) {
+	var log bytes.Buffer
+	const n = 12
+	dir := t.TempDir()
+	want := keys(GenerateRange(context.Background(), n, n, NewPattern(), false, false, nil, 2, "", nil, nil)[0])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := GenerateRange(ctx, n, n, NewPattern(), false, false, nil, 2, dir, &log, func(numTriangles, nodes, accepted int) {
+		cancel()
+	})[0]
+	if ctx.Err() == nil || len(interrupted.patterns) >= len(want) {
+		t.Fatalf("перебор не прерван: фигур %d из %d", len(interrupted.patterns), len(want))
+	}
+	cp, err := readCheckpoint(dir, n)
+	if err != nil || cp == nil {
+		t.Fatalf("контрольная точка не сохранена: %v", err)
+	}
+
+	t.Logf("готово %d, фигур %d", len(cp.Done), len(cp.Entries))
+	got := keys(GenerateRange(context.Background(), n, n, NewPattern(), false, false, nil, 2, dir, &log, nil)[0])
+	if !strings.Contains(log.String(), "продолжение с контрольной точки") {
+		t.Errorf("перебор начат заново: %s", log.String())
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("после продолжения фигур %d, без прерывания %d", len(got), len(want))
+	}
+	if cp, _ = readCheckpoint(dir, n); cp != nil {
+		t.Error("контрольная точка не удалена после окончания перебора")
+	}
+}
+
+func TestCheckpointMismatch(t *testing.T) {
+	dir := t.TempDir()
+	pc := NewCollection()
+	pc.checkpointDir = dir
+	pc.checkpointSize = 6
+	pc.checkpointSeed = "seed"
+	pc.known = map[string]bool{generated(6)[0].getCanonical(): true}
+	valid := checkpoint{
+		Version:      checkpointVersion,
+		NumTriangles: 6,
+		Seed:         "seed",
+		Known:        knownHash(pc.known),
+		Done:         []int{0, 2},
+	}
+	oldVersion := valid
+	oldVersion.Version = checkpointVersion - 1
+	otherKnown := valid
+	otherKnown.Known = ""
+	cases := []struct {
+		name string
+		cp   checkpoint
+		ok   bool
+	}{
+		{"та же", valid, true},
+		{"другая версия", oldVersion, false},
+		{"другие исключённые фигуры", otherKnown, false},
+	}
+	for i := 0; i < len(cases); i++ {
+		if err := writeCheckpoint(dir, cases[i].cp); err != nil {
+			t.Fatal(err)
+		}
+		done := make(map[int]bool)
+		err := pc.loadCheckpoint(&sharedSet{entries: make(map[string]*sharedEntry)}, done)
+		if (err == nil) != cases[i].ok {
+			t.Errorf("%s: ошибка %v", cases[i].name, err)
+		}
+		if cases[i].ok && (len(done) != 2 || !done[0] || !done[2]) {
+			t.Errorf("%s: готовые заготовки %v", cases[i].name, done)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package polyiamond
+
+import (
+	"math"
+	"sort"
+)
+
+// IsConvex сообщает, выпукла ли фигура как многоугольник: у неё нет дыр,
+// а внешний контур при обходе против часовой стрелки поворачивает только
+// влево. У выпуклой фигуры на треугольной сетке не больше шести сторон.
+func (p *Pattern) IsConvex() bool {
+	var prev, v, next [2]float64
+	loops := p.outlineLoops()
+	if len(loops) != 1 {
+		return false
+	}
+	loop := loops[0]
+	for i := 0; i < len(loop); i++ {
+		prev = loop[(i+len(loop)-1)%len(loop)]
+		v = loop[i]
+		next = loop[(i+1)%len(loop)]
+		if (v[0]-prev[0])*(next[1]-v[1])-(v[1]-prev[1])*(next[0]-v[0]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Compactness - отношение площади фигуры к квадрату периметра, умноженное
+// на 4π: у круга было бы 1, у правильного шестиугольника из шести
+// треугольников около 0.91, у вытянутых фигур значение близко к нулю
+func (p *Pattern) Compactness() float64 {
+	perimeter := p.Perimeter()
+	if perimeter == 0 {
+		return 0
+	}
+	area := float64(p.Len()) * math.Sqrt(3) / 4
+	return 4 * math.Pi * area / float64(perimeter*perimeter)
+}
+
+// Filtered возвращает коллекцию из фигур, для которых keep возвращает true,
+// в прежнем порядке; статистика перебора сохраняется
+func (pc *Collection) Filtered(keep func(p *Pattern) bool) *Collection {
+	filtered := NewCollection()
+	filtered.stats = pc.stats
+	for i := 0; i < len(pc.patterns); i++ {
+		if keep(pc.patterns[i]) {
+			filtered.patterns = append(filtered.patterns, pc.patterns[i])
+		}
+	}
+	return filtered
+}
+
+// SortByCompactness упорядочивает фигуры от самых компактных к самым
+// вытянутым; фигуры с равной компактностью остаются в прежнем порядке
+func (pc *Collection) SortByCompactness() {
+	compactness := make(map[*Pattern]float64, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		compactness[pc.patterns[i]] = pc.patterns[i].Compactness()
+	}
+	sort.SliceStable(pc.patterns, func(i, j int) bool {
+		return compactness[pc.patterns[i]] > compactness[pc.patterns[j]]
+	})
+}
@@ -0,0 +1,654 @@
+package polyiamond
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Распределённый перебор: координатор делит дерево перебора Редельмейера
+// на заготовки длины splitAt и раздаёт их номера рабочим по HTTP. Рабочий
+// сам обходит те же заготовки до полученной, перебирает её и отправляет
+// краткие записи найденных фигур; координатор собирает их без повторов
+// и дописывает в текстовый файл, который понимают -load и -merge. Чтобы
+// отбрасывать повторы, координатор держит в памяти все записи текущего
+// размера, но не сами фигуры и не заготовки, поэтому так можно перебрать
+// и n ≥ 18. Готовые заготовки записываются в контрольную точку обычного
+// формата, и после перезапуска координатор продолжает с них.
+
+// заготовка, не вернувшаяся за это время, отдаётся другому рабочему
+const distributedLease = 10 * time.Minute
+
+// пауза рабочего, когда все заготовки розданы, но ещё не готовы
+const distributedRetry = 2 * time.Second
+
+// сколько раз рабочий отправляет запрос при сетевой ошибке или ответе 5xx
+const distributedAttempts = 6
+
+// пауза перед первым повтором запроса; дальше она удваивается
+const distributedBackoff = time.Second
+
+// сколько координатор отвечает "готово" после окончания перебора,
+// чтобы ожидающие рабочие успели завершиться
+const distributedGrace = 3 * distributedRetry
+
+// ответ на GET /task: номер заготовки, Wait - подождать и спросить снова,
+// Done - перебор закончен
+type workUnit struct {
+	NumTriangles int  `json:"num_triangles,omitempty"`
+	SplitAt      int  `json:"split_at,omitempty"`
+	Task         int  `json:"task"`
+	Wait         bool `json:"wait,omitempty"`
+	Done         bool `json:"done,omitempty"`
+}
+
+// тело POST /result
+type workResult struct {
+	NumTriangles int      `json:"num_triangles"`
+	SplitAt      int      `json:"split_at"`
+	Task         int      `json:"task"`
+	Nodes        int      `json:"nodes"`
+	Codes        []string `json:"codes"`
+}
+
+// обходит заготовки перебора Редельмейера длины splitAt, не сохраняя их;
+// при одинаковых параметрах порядок одинаков на любой машине. Состояние
+// заготовки меняется после возврата из visit, поэтому нужную заготовку
+// visit копирует сам; false останавливает обход. Возвращает число
+// пройденных заготовок
+func visitRedelmeierTasks(ctx context.Context, numTriangles, splitAt int, visit func(task splitTask) bool) int {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	producer := &Collection{ctx: ctx, splitAt: splitAt}
+	producer.onTask = func(task splitTask) {
+		if !visit(task) {
+			cancel()
+		}
+	}
+	producer.generateRedelmeier(numTriangles)
+	return producer.numTasks
+}
+
+// все фигуры находятся в заготовках, только если заготовки короче фигур
+func clampSplitAt(numTriangles, splitDepth int) int {
+	return max(1, min(splitDepth, numTriangles-1))
+}
+
+type coordinator struct {
+	mu           sync.Mutex
+	numTriangles int
+	splitAt      int
+	numTasks     int
+	active       bool
+	// перебор всех размеров закончен
+	over       bool
+	done       map[int]bool
+	leased     map[int]time.Time
+	codes      map[string]bool
+	nodes      int
+	results    *os.File
+	finished   chan struct{}
+	onProgress func(nodes, accepted int)
+	log        io.Writer
+}
+
+func (c *coordinator) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /task", c.handleTask)
+	mux.HandleFunc("POST /result", c.handleResult)
+	return mux
+}
+
+func (c *coordinator) handleTask(w http.ResponseWriter, r *http.Request) {
+	var unit workUnit
+	now := time.Now()
+	c.mu.Lock()
+	switch {
+	case c.over:
+		unit.Done = true
+	case !c.active:
+		unit.Wait = true
+	default:
+		unit.Wait = true
+		for i := 0; i < c.numTasks; i++ {
+			if c.done[i] || now.Before(c.leased[i]) {
+				continue
+			}
+			c.leased[i] = now.Add(distributedLease)
+			unit = workUnit{NumTriangles: c.numTriangles, SplitAt: c.splitAt, Task: i}
+			break
+		}
+	}
+	c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(unit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (c *coordinator) handleResult(w http.ResponseWriter, r *http.Request) {
+	var result workResult
+	var p *Pattern
+	var sb strings.Builder
+	err := json.NewDecoder(r.Body).Decode(&result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// записи проверяются до блокировки: это самая долгая часть;
+	// повторы отбрасываются по записи, поэтому она должна быть канонической
+	for i := 0; i < len(result.Codes); i++ {
+		p, err = decodePattern(result.Codes[i])
+		if err == nil && (p.Len() != result.NumTriangles || !p.IsConnected() || p.Encode() != result.Codes[i]) {
+			err = fmt.Errorf("%q - не каноническая запись фигуры из %d треугольников", result.Codes[i], result.NumTriangles)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active || result.NumTriangles != c.numTriangles || result.SplitAt != c.splitAt {
+		http.Error(w, "заготовка относится к другому перебору", http.StatusConflict)
+		return
+	}
+	if result.Task < 0 || result.Task >= c.numTasks {
+		http.Error(w, fmt.Sprintf("нет заготовки %d", result.Task), http.StatusBadRequest)
+		return
+	}
+	// заготовку могли перебрать дважды, если рабочий не уложился в срок
+	if c.done[result.Task] {
+		return
+	}
+	for i := 0; i < len(result.Codes); i++ {
+		if !c.codes[result.Codes[i]] {
+			sb.WriteString(result.Codes[i] + "\n")
+		}
+	}
+	_, err = c.results.WriteString(sb.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := 0; i < len(result.Codes); i++ {
+		c.codes[result.Codes[i]] = true
+	}
+	c.done[result.Task] = true
+	delete(c.leased, result.Task)
+	c.nodes += result.Nodes
+	if c.onProgress != nil {
+		c.onProgress(c.nodes, len(c.codes))
+	}
+	if len(c.done) == c.numTasks {
+		close(c.finished)
+	}
+}
+
+// записи из файла результатов, по одной в строке
+func readCodes(path string) (map[string]bool, error) {
+	codes := make(map[string]bool)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return codes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			codes[line] = true
+		}
+	}
+	return codes, scanner.Err()
+}
+
+// готовые заготовки из контрольной точки того же перебора
+func (c *coordinator) loadDone(checkpointDir, seed string) (map[int]bool, error) {
+	done := make(map[int]bool)
+	if checkpointDir == "" {
+		return done, nil
+	}
+	cp, err := readCheckpoint(checkpointDir, c.numTriangles)
+	if err != nil || cp == nil {
+		return done, err
+	}
+	if cp.Version != checkpointVersion {
+		return done, fmt.Errorf("контрольная точка записана другой версией программы")
+	}
+	if cp.NumTriangles != c.numTriangles || cp.Seed != seed || cp.Placements {
+		return done, fmt.Errorf("контрольная точка относится к другому запуску")
+	}
+	for i := 0; i < len(cp.Done); i++ {
+		done[cp.Done[i]] = true
+	}
+	logf(c.log, "%d: продолжение с контрольной точки, готово заготовок %d\n", cp.NumTriangles, len(cp.Done))
+	return done, nil
+}
+
+func (c *coordinator) saveDone(checkpointDir, seed string) error {
+	cp := checkpoint{Version: checkpointVersion, NumTriangles: c.numTriangles, Seed: seed}
+	c.mu.Lock()
+	for task := range c.done {
+		cp.Done = append(cp.Done, task)
+	}
+	c.mu.Unlock()
+	sort.Ints(cp.Done)
+	return writeCheckpoint(checkpointDir, cp)
+}
+
+// перебор одного размера: ждёт, пока рабочие вернут все заготовки,
+// и возвращает число найденных фигур
+func (c *coordinator) run(ctx context.Context, path, checkpointDir string) (int, error) {
+	var codes map[string]bool
+	numTasks := visitRedelmeierTasks(ctx, c.numTriangles, c.splitAt, func(splitTask) bool {
+		return true
+	})
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	// номера заготовок зависят от их длины, поэтому точка обычного
+	// перебора или перебора с другой длиной заготовок не подходит
+	seed := fmt.Sprintf("distributed:%d", c.splitAt)
+	done, err := c.loadDone(checkpointDir, seed)
+	if err != nil {
+		logf(c.log, "%v, перебор начинается заново\n", err)
+		done = make(map[int]bool)
+	}
+	// без контрольной точки прежние результаты не нужны
+	if len(done) == 0 {
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	codes, err = readCodes(path)
+	if err != nil {
+		return 0, err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return 0, err
+	}
+	results, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	finished := make(chan struct{})
+	if len(done) == numTasks {
+		close(finished)
+	}
+	c.mu.Lock()
+	c.numTasks = numTasks
+	c.done = done
+	c.leased = make(map[int]time.Time)
+	c.codes = codes
+	c.nodes = 0
+	c.results = results
+	c.finished = finished
+	c.active = true
+	c.mu.Unlock()
+
+	var ticker <-chan time.Time
+	if checkpointDir != "" {
+		t := time.NewTicker(checkpointInterval)
+		defer t.Stop()
+		ticker = t.C
+	}
+	waiting := true
+	for waiting {
+		select {
+		case <-ticker:
+			if err = c.saveDone(checkpointDir, seed); err != nil {
+				logf(c.log, "%v\n", err)
+			}
+		case <-finished:
+			waiting = false
+		case <-ctx.Done():
+			waiting = false
+		}
+	}
+	c.mu.Lock()
+	c.active = false
+	count := len(c.codes)
+	c.mu.Unlock()
+	err = results.Close()
+	if err != nil {
+		return count, err
+	}
+	if ctx.Err() != nil {
+		if checkpointDir != "" {
+			if err = c.saveDone(checkpointDir, seed); err != nil {
+				logf(c.log, "%v\n", err)
+			}
+		}
+		return count, ctx.Err()
+	}
+	if checkpointDir != "" {
+		err = os.Remove(checkpointPath(checkpointDir, c.numTriangles))
+		if err != nil && !os.IsNotExist(err) {
+			logf(c.log, "%v\n", err)
+		}
+	}
+	return count, sortCodesFile(path, c.codes)
+}
+
+// перезаписывает файл результатов в порядке записей, чтобы он не зависел
+// от того, в каком порядке рабочие вернули заготовки
+func sortCodesFile(path string, codes map[string]bool) error {
+	var sb strings.Builder
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Strings(sorted)
+	for i := 0; i < len(sorted); i++ {
+		sb.WriteString(sorted[i] + "\n")
+	}
+	err := os.WriteFile(path+".tmp", []byte(sb.String()), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Coordinate раздаёт рабочим по адресу addr перебор фигур всех размеров
+// от minTriangles до maxTriangles и записывает краткие записи найденных
+// фигур в dir/<n>/patterns.txt; возвращает число фигур каждого размера.
+// Заготовки - начала фигур длины splitDepth: чем они длиннее, тем их
+// больше и тем мельче работа каждого рабочего. При отмене ctx последнее
+// число неполное, а с checkpointDir состояние сохраняется для продолжения.
+// Сообщения о контрольных точках пишутся в log, если он не nil.
+func Coordinate(ctx context.Context, addr string, minTriangles, maxTriangles, splitDepth int, dir, checkpointDir string, log io.Writer, onProgress func(numTriangles, nodes, accepted int)) ([]int, error) {
+	c := &coordinator{log: log}
+	server := &http.Server{Handler: c.routes()}
+	// адрес занимается сразу, чтобы ошибка была видна до перебора
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	counts := make([]int, 0, maxTriangles-minTriangles+1)
+	for n := minTriangles; n <= maxTriangles; n++ {
+		c.mu.Lock()
+		c.numTriangles = n
+		c.splitAt = clampSplitAt(n, splitDepth)
+		c.onProgress = nil
+		if onProgress != nil {
+			numTriangles := n
+			c.onProgress = func(nodes, accepted int) {
+				onProgress(numTriangles, nodes, accepted)
+			}
+		}
+		c.mu.Unlock()
+		count, err := c.run(ctx, filepath.Join(dir, fmt.Sprint(n), "patterns.txt"), checkpointDir)
+		counts = append(counts, count)
+		if err != nil {
+			return counts, err
+		}
+	}
+	c.mu.Lock()
+	c.over = true
+	c.mu.Unlock()
+	select {
+	case <-time.After(distributedGrace):
+	case <-ctx.Done():
+	}
+	return counts, nil
+}
+
+type worker struct {
+	ctx    context.Context
+	url    string
+	client *http.Client
+	// сообщения о повторах запросов; nil - без них
+	log io.Writer
+	mu  sync.Mutex
+	// обходы заготовок по размеру фигур и длине заготовок
+	cursors map[[2]int]*taskCursor
+}
+
+// ленивый обход заготовок: очередная строится по запросу её номера,
+// пройденные не хранятся. Координатор раздаёт заготовки по возрастанию
+// номеров, поэтому обход почти всегда продолжается с места остановки
+type taskCursor struct {
+	// номер заготовки, следующей за последней выданной
+	next   int
+	want   chan int
+	found  chan splitTask
+	cancel context.CancelFunc
+}
+
+func newTaskCursor(ctx context.Context, numTriangles, splitAt int) *taskCursor {
+	ctx, cancel := context.WithCancel(ctx)
+	cur := &taskCursor{want: make(chan int, 1), found: make(chan splitTask), cancel: cancel}
+	go func() {
+		defer close(cur.found)
+		target := -1
+		visitRedelmeierTasks(ctx, numTriangles, splitAt, func(task splitTask) bool {
+			if target < 0 {
+				select {
+				case target = <-cur.want:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			if task.index < target {
+				return true
+			}
+			target = -1
+			task.search = task.search.getCopy()
+			select {
+			case cur.found <- task:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return cur
+}
+
+// заготовка номер index; false, если заготовок меньше или ctx отменён
+func (wk *worker) getTask(numTriangles, splitAt, index int) (splitTask, bool) {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+	key := [2]int{numTriangles, splitAt}
+	for k, cur := range wk.cursors {
+		// обход прошёл нужную заготовку или относится к другому перебору
+		if k != key || index < cur.next {
+			cur.cancel()
+			delete(wk.cursors, k)
+		}
+	}
+	cur, ok := wk.cursors[key]
+	if !ok {
+		cur = newTaskCursor(wk.ctx, numTriangles, splitAt)
+		wk.cursors[key] = cur
+	}
+	cur.want <- index
+	task, ok := <-cur.found
+	if !ok {
+		delete(wk.cursors, key)
+		return splitTask{}, false
+	}
+	cur.next = index + 1
+	return task, true
+}
+
+// отправляет запрос, повторяя его с растущей паузой при сетевых
+// ошибках и ответах 5xx: координатор мог перезапускаться
+func (wk *worker) request(method, path string, body, reply any) error {
+	var data []byte
+	var err error
+	var retry bool
+	if body != nil {
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	delay := distributedBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err = wk.send(method, path, data, reply)
+		if !retry || attempt == distributedAttempts {
+			return err
+		}
+		logf(wk.log, "%v, повтор через %v\n", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-wk.ctx.Done():
+			return wk.ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// одна попытка запроса; retry - ошибка временная и запрос стоит повторить
+func (wk *worker) send(method, path string, data []byte, reply any) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(wk.ctx, method, wk.url+path, bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	resp, err := wk.client.Do(req)
+	if err != nil {
+		return wk.ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		var msg bytes.Buffer
+		msg.ReadFrom(resp.Body)
+		err = fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(msg.String()))
+		return resp.StatusCode >= http.StatusInternalServerError, err
+	}
+	if reply == nil {
+		return false, nil
+	}
+	return false, json.NewDecoder(resp.Body).Decode(reply)
+}
+
+// перебирает заготовку и возвращает краткие записи найденных фигур
+func (wk *worker) process(unit workUnit) (workResult, error) {
+	var task splitTask
+	var ok bool
+	if unit.Task >= 0 {
+		task, ok = wk.getTask(unit.NumTriangles, unit.SplitAt, unit.Task)
+	}
+	if !ok && wk.ctx.Err() != nil {
+		return workResult{}, wk.ctx.Err()
+	}
+	if !ok {
+		return workResult{}, fmt.Errorf("нет заготовки %d: другая версия программы у координатора?", unit.Task)
+	}
+	local := &Collection{
+		ctx:    wk.ctx,
+		shared: &sharedSet{entries: make(map[string]*sharedEntry)},
+		task:   unit.Task,
+	}
+	local.extendRedelmeier(task.search, task.untried, task.toAdd)
+	entries := make([]*sharedEntry, 0, len(local.shared.entries))
+	for _, e := range local.shared.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+	result := workResult{
+		NumTriangles: unit.NumTriangles,
+		SplitAt:      unit.SplitAt,
+		Task:         unit.Task,
+		Nodes:        local.stats.nodes,
+		Codes:        make([]string, len(entries)),
+	}
+	for i := 0; i < len(entries); i++ {
+		result.Codes[i] = entries[i].p.Encode()
+	}
+	return result, wk.ctx.Err()
+}
+
+// Work получает заготовки от координатора по адресу url и перебирает их
+// в jobs потоков, пока координатор не сообщит об окончании перебора
+// или не будет отменён ctx; возвращает число перебранных заготовок.
+// О повторах запросов сообщается в log, если он не nil.
+func Work(ctx context.Context, url string, jobs int, log io.Writer) (int, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	processed := 0
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wk := &worker{
+		ctx:     workCtx,
+		url:     strings.TrimSuffix(url, "/"),
+		client:  &http.Client{},
+		log:     log,
+		cursors: make(map[[2]int]*taskCursor),
+	}
+	for w := 0; w < max(jobs, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var unit workUnit
+			var result workResult
+			var err error
+			for workCtx.Err() == nil {
+				unit = workUnit{}
+				err = wk.request(http.MethodGet, "/task", nil, &unit)
+				if err == nil && unit.Wait {
+					select {
+					case <-time.After(distributedRetry):
+					case <-workCtx.Done():
+					}
+					continue
+				}
+				if err == nil && unit.Done {
+					return
+				}
+				if err == nil {
+					result, err = wk.process(unit)
+				}
+				if err == nil {
+					err = wk.request(http.MethodPost, "/result", result, nil)
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil && !errors.Is(err, context.Canceled) {
+						firstErr = err
+					}
+					mu.Unlock()
+					cancel()
+					return
+				}
+				mu.Lock()
+				processed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return processed, firstErr
+	}
+	return processed, ctx.Err()
+}
@@ -0,0 +1,87 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// координатор и два рабочих в одном процессе находят те же фигуры,
+// что и обычный перебор
+func TestDistributedMatchesGenerate(t *testing.T) {
+	var wg sync.WaitGroup
+	var want, got map[string]bool
+	var count int
+	var err error
+	dir := t.TempDir()
+	c := &coordinator{}
+	server := httptest.NewServer(c.routes())
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Work(ctx, server.URL, 1, nil); err != nil {
+				t.Errorf("рабочий %d: %v", w, err)
+			}
+		}()
+	}
+	// заготовки короче фигуры, поэтому фигуры из одного треугольника
+	// распределённо не перебираются
+	for n := 2; n <= 9; n++ {
+		c.mu.Lock()
+		c.numTriangles = n
+		c.splitAt = clampSplitAt(n, 4)
+		c.mu.Unlock()
+		path := filepath.Join(dir, fmt.Sprint(n), "patterns.txt")
+		count, err = c.run(ctx, path, "")
+		if err != nil {
+			t.Fatalf("%d треугольников: %v", n, err)
+		}
+		want = make(map[string]bool)
+		NewCollection().Generate(ctx, n, func(p *Pattern) bool {
+			want[p.Encode()] = true
+			return true
+		})
+		got, err = readCodes(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(want) || !maps.Equal(got, want) {
+			t.Errorf("%d треугольников: найдено %d, записано %d, ожидалось %d", n, count, len(got), len(want))
+		}
+	}
+	c.mu.Lock()
+	c.over = true
+	c.mu.Unlock()
+	wg.Wait()
+}
+
+func TestDistributedCheckpointVersion(t *testing.T) {
+	dir := t.TempDir()
+	c := &coordinator{numTriangles: 8, done: map[int]bool{1: true, 3: true}}
+	if err := c.saveDone(dir, "seed"); err != nil {
+		t.Fatal(err)
+	}
+	done, err := c.loadDone(dir, "seed")
+	if err != nil || !maps.Equal(done, c.done) {
+		t.Errorf("прочитаны заготовки %v, %v, сохранены %v", done, err, c.done)
+	}
+	cp, err := readCheckpoint(dir, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.Version = checkpointVersion - 1
+	if err = writeCheckpoint(dir, *cp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.loadDone(dir, "seed"); err == nil {
+		t.Error("контрольная точка другой версии принята")
+	}
+}
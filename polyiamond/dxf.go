@@ -0,0 +1,77 @@
+package polyiamond
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// длина стороны треугольника в миллиметрах по умолчанию
+const defaultEdgeMM = 20.0
+
+// WritePatternDXF записывает контуры фигуры замкнутыми полилиниями DXF
+// в миллиметрах: внутренние стороны треугольников не выводятся, только
+// внешняя граница и границы дыр. edgeMM - длина стороны треугольника.
+func WritePatternDXF(w io.Writer, p *Pattern, edgeMM float64) error {
+	var sb strings.Builder
+	var loop []Point
+	if edgeMM <= 0 {
+		edgeMM = defaultEdgeMM
+	}
+	xMin, yMin, _, _ := p.cartesianBounds()
+
+	// $INSUNITS = 4 - миллиметры
+	sb.WriteString("0\nSECTION\n2\nHEADER\n9\n$INSUNITS\n70\n4\n0\nENDSEC\n")
+	sb.WriteString("0\nSECTION\n2\nENTITIES\n")
+	outer, holes := p.outline()
+	loops := append([][]Point{outer}, holes...)
+	for i := 0; i < len(loops); i++ {
+		loop = loops[i]
+		sb.WriteString("0\nPOLYLINE\n8\n0\n66\n1\n10\n0.0\n20\n0.0\n30\n0.0\n70\n1\n")
+		for j := 0; j < len(loop); j++ {
+			fmt.Fprintf(&sb, "0\nVERTEX\n8\n0\n10\n%.4f\n20\n%.4f\n30\n0.0\n",
+				(loop[j].X-xMin)*edgeMM, (loop[j].Y-yMin)*edgeMM)
+		}
+		sb.WriteString("0\nSEQEND\n8\n0\n")
+	}
+	sb.WriteString("0\nENDSEC\n0\nEOF\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func saveAsDXF(p *Pattern, path string, edgeMM float64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WritePatternDXF(f, p, edgeMM)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SaveDXF сохраняет в dir контур каждой фигуры под именем её
+// изображения с расширением .dxf
+func (pc *Collection) SaveDXF(dir string, opts RenderOptions, edgeMM float64) error {
+	var name string
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(pc.patterns); i++ {
+		name = opts.filenameAs(i, pc.patterns[i], "dxf")
+		err = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		if err != nil {
+			return err
+		}
+		err = saveAsDXF(pc.patterns[i], filepath.Join(dir, name), edgeMM)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
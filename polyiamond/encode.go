@@ -0,0 +1,99 @@
+package polyiamond
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Краткая запись фигуры: каноническая форма в кубических координатах,
+// сдвинутая так, что наименьшие a и b равны нулю. Строки соответствуют b,
+// символы строки - a; цифра описывает пару треугольников с общими a и b:
+// 1 - "нижний", 2 - "верхний", 3 - оба, 0 - ни одного. Строки разделены
+// знаком "-", нули в конце строки опускаются. Например, ромб - "3",
+// одиночный треугольник - "2". Запись годится для имён файлов.
+
+const encodingRowSeparator = "-"
+
+// Encode возвращает краткую запись фигуры; у совпадающих с точностью
+// до поворотов, отражений и сдвигов фигур записи одинаковые
+func (p *Pattern) Encode() string {
+	var a, b, c, minA, minB, maxB int
+	var t *Triangle
+	canonical := p.getCanonical()
+	cells := make(map[[2]int]int, p.Len())
+	for i := 0; i+8 <= len(canonical); i += 8 {
+		t = unpackTriangle(binary.BigEndian.Uint64([]byte(canonical[i : i+8])))
+		a, b, _ = t.toCube()
+		if i == 0 || a < minA {
+			minA = a
+		}
+		if i == 0 || b < minB {
+			minB = b
+		}
+		if i == 0 || b > maxB {
+			maxB = b
+		}
+	}
+	for i := 0; i+8 <= len(canonical); i += 8 {
+		t = unpackTriangle(binary.BigEndian.Uint64([]byte(canonical[i : i+8])))
+		a, b, c = t.toCube()
+		if a+b+c == 2 {
+			cells[[2]int{a - minA, b - minB}] |= 2
+		} else {
+			cells[[2]int{a - minA, b - minB}] |= 1
+		}
+	}
+	rows := make([]string, maxB-minB+1)
+	for key, v := range cells {
+		row := []byte(rows[key[1]])
+		for len(row) <= key[0] {
+			row = append(row, '0')
+		}
+		row[key[0]] = byte('0' + v)
+		rows[key[1]] = string(row)
+	}
+	return strings.Join(rows, encodingRowSeparator)
+}
+
+// isEncoded сообщает, похожа ли строка на краткую запись, а не на координаты
+func isEncoded(s string) bool {
+	return s != "" && strings.Trim(s, "0123"+encodingRowSeparator) == ""
+}
+
+// decodePattern восстанавливает фигуру по краткой записи
+func decodePattern(s string) (*Pattern, error) {
+	var t *Triangle
+	var err error
+	p := NewPattern()
+	rows := strings.Split(s, encodingRowSeparator)
+	for b := 0; b < len(rows); b++ {
+		for a := 0; a < len(rows[b]); a++ {
+			v := rows[b][a] - '0'
+			if v > 3 {
+				return nil, fmt.Errorf("неверная краткая запись %q", s)
+			}
+			if v&1 != 0 {
+				t, err = triangleFromCube(a, b, 1-a-b)
+				if err != nil {
+					return nil, err
+				}
+				p.addTriangle(t)
+			}
+			if v&2 != 0 {
+				t, err = triangleFromCube(a, b, 2-a-b)
+				if err != nil {
+					return nil, err
+				}
+				p.addTriangle(t)
+			}
+		}
+	}
+	if p.Len() == 0 {
+		return nil, fmt.Errorf("краткая запись %q не содержит треугольников", s)
+	}
+	if err = validatePattern(p); err != nil {
+		return nil, fmt.Errorf("краткая запись %q: %w", s, err)
+	}
+	return p, nil
+}
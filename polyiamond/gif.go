@@ -0,0 +1,125 @@
+package polyiamond
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const growthFrameDelay = 50
+const growthLastFrameDelay = 200
+const growthHighlightColor = "#fd8d3c"
+const growthFillColor = "#c6dbef"
+
+// порядок, в котором генератор добавлял треугольники: шаги построения
+// повторяются от затравки, а при пустой затравке - от треугольника,
+// с которого генератор начал построение этой фигуры
+func (p *Pattern) growthOrder(seed *Pattern) (*Pattern, error) {
+	start := seed.getCopy()
+	if start.Len() == 0 {
+		if p.buildRoot == (Triangle{}) {
+			return nil, fmt.Errorf("для фигуры не записаны шаги построения")
+		}
+		start.addTriangle(&p.buildRoot)
+	}
+	grown, err := replayBuild(start, p.getBuildSteps())
+	if err != nil {
+		return nil, err
+	}
+	if grown.Len() != p.Len() {
+		return nil, fmt.Errorf("для фигуры не записаны шаги построения")
+	}
+	// построенная фигура сдвигается на место сохранённой: наименьшие
+	// треугольники совмещаются
+	from, to := grown.getSortedTriangles()[0], p.getSortedTriangles()[0]
+	dx, dy := to.x-from.x, to.y-from.y
+	grown = grown.getShifted(dx, 3).getShifted(dx+dy, 1)
+	if !isSameTriangles(grown.getSortedTriangles(), p.getSortedTriangles()) {
+		return nil, fmt.Errorf("шаги построения не соответствуют фигуре")
+	}
+	return grown, nil
+}
+
+// WriteGrowthGIF записывает анимацию построения фигуры: по кадру на каждый
+// добавленный треугольник, новый треугольник выделен цветом
+func WriteGrowthGIF(w io.Writer, p *Pattern, seed *Pattern, opts RenderOptions) error {
+	var partial *Pattern
+	var frame *image.Paletted
+	grown, err := p.growthOrder(seed)
+	if err != nil {
+		return err
+	}
+	anim := &gif.GIF{}
+	for k := 1; k <= grown.Len(); k++ {
+		partial = NewPattern()
+		for i := 0; i < k; i++ {
+			partial.addTriangle(&grown.triangles[i])
+		}
+		pimg := newPatternImage(opts)
+		pimg.drawBackground(pimg.setView(grown))
+		for i := 0; i < k; i++ {
+			switch {
+			case i == k-1:
+				pimg.img.SetHexColor(growthHighlightColor)
+			case opts.isFilled():
+				pimg.img.SetHexColor(opts.fillColor(partial, i))
+			default:
+				pimg.img.SetHexColor(growthFillColor)
+			}
+			pimg.fillTriangle(&partial.triangles[i])
+		}
+		pimg.drawLines(partial.edgeLines())
+		if opts.ShowIndex {
+			pimg.drawIndices(partial)
+		}
+		src := pimg.img.Image()
+		frame = image.NewPaletted(src.Bounds(), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), src, src.Bounds().Min, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, growthFrameDelay)
+	}
+	anim.Delay[len(anim.Delay)-1] = growthLastFrameDelay
+	return gif.EncodeAll(w, anim)
+}
+
+func saveGrowthGIF(p, seed *Pattern, path string, opts RenderOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteGrowthGIF(f, p, seed, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SaveGrowthGIFs сохраняет в dir анимацию построения каждой фигуры
+// под именем её изображения с расширением .gif, в jobs потоков
+func (pc *Collection) SaveGrowthGIFs(dir string, seed *Pattern, opts RenderOptions, jobs int) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	errs := forEachParallel(len(pc.patterns), jobs, func(i int) error {
+		name := opts.filenameAs(i, pc.patterns[i], "gif")
+		err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		if err != nil {
+			return err
+		}
+		err = saveGrowthGIF(pc.patterns[i], seed, filepath.Join(dir, name), opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
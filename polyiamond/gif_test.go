@@ -0,0 +1,53 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func checkGrowthOrder(t *testing.T, name string, ps []*Pattern) {
+	t.Helper()
+	for i := 0; i < len(ps); i++ {
+		grown, err := ps[i].growthOrder(NewPattern())
+		if err != nil {
+			t.Fatalf("%s, фигура %d: %v", name, i, err)
+		}
+		if !isSameTriangles(grown.getSortedTriangles(), ps[i].getSortedTriangles()) {
+			t.Errorf("%s, фигура %d: построение %s, фигура %s", name, i, grown.Encode(), ps[i].Encode())
+		}
+	}
+}
+
+// последний кадр анимации - фигура в том же положении, что и на изображении
+func TestGrowthOrderMatchesPattern(t *testing.T) {
+	for _, placements := range []bool{false, true} {
+		for _, workers := range []int{1, 3} {
+			ps := GenerateRange(context.Background(), 7, 7, NewPattern(), placements, true, nil, workers, "", nil, nil)[0].Patterns()
+			checkGrowthOrder(t, fmt.Sprintf("placements=%v workers=%d", placements, workers), ps)
+		}
+	}
+	collections, err := GenerateUpTo(context.Background(), 7, true, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(collections); i++ {
+		checkGrowthOrder(t, fmt.Sprintf("upto, %d треугольников", i+1), collections[i].Patterns())
+	}
+}
+
+// шаги построения, которые не повторяются от начального треугольника,
+// дают ошибку, а не пропуск продолжений фигуры
+func TestExtensionsBrokenSteps(t *testing.T) {
+	start := NewPattern()
+	start.addTriangle(newTriangle(0, 1, 0))
+	p := mustParse(t, diamond)
+	p.buildSteps = []buildStep{{index: 3, axis: 1}}
+	if _, err := extensions(p, start, true); err == nil {
+		t.Error("неверные шаги построения приняты")
+	}
+	p.buildSteps = []buildStep{{index: 0, axis: 1}}
+	if ext, err := extensions(p, start, true); err != nil || len(ext) == 0 {
+		t.Errorf("продолжений %d, ошибка %v", len(ext), err)
+	}
+}
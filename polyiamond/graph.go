@@ -0,0 +1,127 @@
+package polyiamond
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ребро двойственного графа фигуры: треугольники с номерами from и to
+// соседствуют по оси axis
+type dualEdge struct {
+	from int
+	to   int
+	axis int
+}
+
+// двойственный граф фигуры: вершины - треугольники в порядке
+// p.triangles, рёбра - общие стороны треугольников
+func (p *Pattern) dualEdges() []dualEdge {
+	var neighbour *Triangle
+	var edges []dualEdge
+	index := make(map[Triangle]int, len(p.triangles))
+	for i := 0; i < len(p.triangles); i++ {
+		index[p.triangles[i]] = i
+	}
+	for i := 0; i < len(p.triangles); i++ {
+		for axis := 1; axis <= 3; axis++ {
+			neighbour = p.triangles[i].getNeighbour(axis)
+			if j, ok := index[*neighbour]; ok && i < j {
+				edges = append(edges, dualEdge{from: i, to: j, axis: axis})
+			}
+		}
+	}
+	return edges
+}
+
+// WritePatternDOT записывает граф смежности треугольников фигуры на языке
+// DOT (Graphviz): вершина - треугольник с координатами в подписи,
+// ребро - общая сторона двух треугольников с номером оси в атрибуте axis
+func WritePatternDOT(w io.Writer, p *Pattern) error {
+	var sb strings.Builder
+	var t *Triangle
+	fmt.Fprintf(&sb, "graph %q {\n", p.Encode())
+	for i := 0; i < len(p.triangles); i++ {
+		t = &p.triangles[i]
+		shape := "triangle"
+		if !t.isUpward() {
+			shape = "invtriangle"
+		}
+		fmt.Fprintf(&sb, "  %d [label=\"%d,%d,%d\", shape=%s];\n", i, t.x, t.y, t.z, shape)
+	}
+	edges := p.dualEdges()
+	for i := 0; i < len(edges); i++ {
+		fmt.Fprintf(&sb, "  %d -- %d [axis=%d];\n", edges[i].from, edges[i].to, edges[i].axis)
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WritePatternGraphML записывает тот же граф в GraphML: у вершин
+// атрибуты coords и up ("верхний" треугольник), у рёбер - axis
+func WritePatternGraphML(w io.Writer, p *Pattern) error {
+	var sb strings.Builder
+	var t *Triangle
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sb.WriteString("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	sb.WriteString("  <key id=\"coords\" for=\"node\" attr.name=\"coords\" attr.type=\"string\"/>\n")
+	sb.WriteString("  <key id=\"up\" for=\"node\" attr.name=\"up\" attr.type=\"boolean\"/>\n")
+	sb.WriteString("  <key id=\"axis\" for=\"edge\" attr.name=\"axis\" attr.type=\"int\"/>\n")
+	fmt.Fprintf(&sb, "  <graph id=\"%s\" edgedefault=\"undirected\">\n", p.Encode())
+	for i := 0; i < len(p.triangles); i++ {
+		t = &p.triangles[i]
+		fmt.Fprintf(&sb, "    <node id=\"n%d\"><data key=\"coords\">%d,%d,%d</data><data key=\"up\">%t</data></node>\n",
+			i, t.x, t.y, t.z, t.isUpward())
+	}
+	edges := p.dualEdges()
+	for i := 0; i < len(edges); i++ {
+		fmt.Fprintf(&sb, "    <edge source=\"n%d\" target=\"n%d\"><data key=\"axis\">%d</data></edge>\n",
+			edges[i].from, edges[i].to, edges[i].axis)
+	}
+	sb.WriteString("  </graph>\n</graphml>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func saveGraph(p *Pattern, path, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if format == "graphml" {
+		err = WritePatternGraphML(f, p)
+	} else {
+		err = WritePatternDOT(f, p)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SaveGraphs сохраняет в dir граф смежности треугольников каждой фигуры
+// в формате dot или graphml под именем её изображения с этим расширением
+func (pc *Collection) SaveGraphs(dir string, opts RenderOptions, format string) error {
+	var name string
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(pc.patterns); i++ {
+		name = opts.filenameAs(i, pc.patterns[i], format)
+		err = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		if err != nil {
+			return err
+		}
+		err = saveGraph(pc.patterns[i], filepath.Join(dir, name), format)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,491 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// Cell - клетка решётки в координатах этой решётки
+type Cell [3]int
+
+// Grid описывает решётку для перечисления фигур из одинаковых клеток,
+// соединённых сторонами
+type Grid interface {
+	// клетка, с которой начинается построение
+	Origin() Cell
+	Neighbours(c Cell) []Cell
+	// число поворотов и отражений решётки, переводящих её в себя
+	NumSymmetries() int
+	Transform(c Cell, k int) Cell
+	// сдвигает отсортированные клетки в положение, одинаковое для всех
+	// сдвигов фигуры
+	Normalize(cells []Cell)
+	// вершины клетки в декартовых координатах
+	Polygon(c Cell) []Point
+}
+
+// GridByName возвращает решётку square или hex; фигуры треугольной
+// решётки перебирает основной генератор пакета
+func GridByName(name string) (Grid, error) {
+	switch name {
+	case "square":
+		return squareGrid{}, nil
+	case "hex":
+		return hexGrid{}, nil
+	}
+	return nil, fmt.Errorf("неизвестная решётка %q", name)
+}
+
+// квадратная решётка: клетка x, y
+type squareGrid struct{}
+
+func (squareGrid) Origin() Cell {
+	return Cell{}
+}
+
+func (squareGrid) Neighbours(c Cell) []Cell {
+	return []Cell{{c[0] + 1, c[1], 0}, {c[0] - 1, c[1], 0}, {c[0], c[1] + 1, 0}, {c[0], c[1] - 1, 0}}
+}
+
+func (squareGrid) NumSymmetries() int {
+	return 8
+}
+
+func (squareGrid) Transform(c Cell, k int) Cell {
+	x, y := c[0], c[1]
+	for i := 0; i < k%4; i++ {
+		x, y = -y, x
+	}
+	if k >= 4 {
+		y = -y
+	}
+	return Cell{x, y, 0}
+}
+
+func (squareGrid) Normalize(cells []Cell) {
+	shiftCells(cells, Cell{})
+}
+
+func (squareGrid) Polygon(c Cell) []Point {
+	x, y := float64(c[0]), float64(c[1])
+	return []Point{{x, y}, {x + 1, y}, {x + 1, y + 1}, {x, y + 1}}
+}
+
+// шестиугольная решётка в кубических координатах q, r, s, q+r+s = 0
+type hexGrid struct{}
+
+func (hexGrid) Origin() Cell {
+	return Cell{}
+}
+
+func (hexGrid) Neighbours(c Cell) []Cell {
+	q, r, s := c[0], c[1], c[2]
+	return []Cell{
+		{q + 1, r - 1, s}, {q + 1, r, s - 1}, {q, r + 1, s - 1},
+		{q - 1, r + 1, s}, {q - 1, r, s + 1}, {q, r - 1, s + 1},
+	}
+}
+
+func (hexGrid) NumSymmetries() int {
+	return 12
+}
+
+func (hexGrid) Transform(c Cell, k int) Cell {
+	q, r, s := c[0], c[1], c[2]
+	for i := 0; i < k%6; i++ {
+		q, r, s = -r, -s, -q
+	}
+	if k >= 6 {
+		r, s = s, r
+	}
+	return Cell{q, r, s}
+}
+
+func (hexGrid) Normalize(cells []Cell) {
+	shiftCells(cells, Cell{})
+}
+
+func (hexGrid) Polygon(c Cell) []Point {
+	var angle float64
+	cx := math.Sqrt(3) * (float64(c[0]) + float64(c[1])/2)
+	cy := 1.5 * float64(c[1])
+	points := make([]Point, 6)
+	for i := 0; i < 6; i++ {
+		angle = math.Pi/6 + float64(i)*math.Pi/3
+		points[i] = Point{cx + math.Cos(angle), cy + math.Sin(angle)}
+	}
+	return points
+}
+
+// переносит первую клетку в target, остальные - на тот же вектор
+func shiftCells(cells []Cell, target Cell) {
+	d := Cell{target[0] - cells[0][0], target[1] - cells[0][1], target[2] - cells[0][2]}
+	for i := 0; i < len(cells); i++ {
+		cells[i] = Cell{cells[i][0] + d[0], cells[i][1] + d[1], cells[i][2] + d[2]}
+	}
+}
+
+func compareCells(a, b Cell) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+func cellsKey(cells []Cell) string {
+	buf := make([]byte, 0, len(cells)*12)
+	for i := 0; i < len(cells); i++ {
+		for j := 0; j < 3; j++ {
+			buf = strconv.AppendInt(buf, int64(cells[i][j]), 10)
+			buf = append(buf, ',')
+		}
+	}
+	return string(buf)
+}
+
+// каноническое положение фигуры: наименьшее по ключу среди всех
+// поворотов и отражений, сдвинутых функцией Normalize
+func canonicalCells(g Grid, cells []Cell) ([]Cell, string) {
+	var best []Cell
+	var bestKey, key string
+	transformed := make([]Cell, len(cells))
+	for k := 0; k < g.NumSymmetries(); k++ {
+		for i := 0; i < len(cells); i++ {
+			transformed[i] = g.Transform(cells[i], k)
+		}
+		slices.SortFunc(transformed, compareCells)
+		g.Normalize(transformed)
+		key = cellsKey(transformed)
+		if best == nil || key < bestKey {
+			best = slices.Clone(transformed)
+			bestKey = key
+		}
+	}
+	return best, bestKey
+}
+
+// GenerateGrid перечисляет фигуры из numCells клеток решётки g
+// с точностью до поворотов, отражений и сдвигов. Фигуры строятся по слоям:
+// каждая фигура из k клеток дополняется всеми соседними клетками,
+// повторы отбрасываются по канонической форме. Фигуры упорядочены
+// по каноническим формам.
+func GenerateGrid(ctx context.Context, g Grid, numCells int) [][]Cell {
+	var grown []Cell
+	var key string
+	var members map[Cell]bool
+	var neighbours []Cell
+	origin, _ := canonicalCells(g, []Cell{g.Origin()})
+	level := [][]Cell{origin}
+	for size := 1; size < numCells && ctx.Err() == nil; size++ {
+		seen := make(map[string]bool)
+		next := make([][]Cell, 0, len(level)*2)
+		for i := 0; i < len(level) && ctx.Err() == nil; i++ {
+			members = make(map[Cell]bool, len(level[i]))
+			for j := 0; j < len(level[i]); j++ {
+				members[level[i][j]] = true
+			}
+			for j := 0; j < len(level[i]); j++ {
+				neighbours = g.Neighbours(level[i][j])
+				for k := 0; k < len(neighbours); k++ {
+					if members[neighbours[k]] {
+						continue
+					}
+					grown = append(slices.Clone(level[i]), neighbours[k])
+					grown, key = canonicalCells(g, grown)
+					if !seen[key] {
+						seen[key] = true
+						next = append(next, grown)
+					}
+				}
+			}
+		}
+		slices.SortFunc(next, func(a, b []Cell) int {
+			return slices.CompareFunc(a, b, compareCells)
+		})
+		level = next
+	}
+	return level
+}
+
+// сторона клетки без учёта направления
+func edgeKey(p, q Point) [2][2]int64 {
+	a := vertexKey([2]float64{p.X, p.Y})
+	b := vertexKey([2]float64{q.X, q.Y})
+	if b[0] < a[0] || b[0] == a[0] && b[1] < a[1] {
+		a, b = b, a
+	}
+	return [2][2]int64{a, b}
+}
+
+// многоугольники клеток фигуры и число клеток, которым принадлежит
+// каждая сторона: граница фигуры - стороны ровно одной клетки
+func gridPolygons(g Grid, cells []Cell) ([][]Point, map[[2][2]int64]int) {
+	var poly []Point
+	polygons := make([][]Point, len(cells))
+	count := make(map[[2][2]int64]int)
+	for i := 0; i < len(cells); i++ {
+		poly = g.Polygon(cells[i])
+		polygons[i] = poly
+		for j := 0; j < len(poly); j++ {
+			count[edgeKey(poly[j], poly[(j+1)%len(poly)])]++
+		}
+	}
+	return polygons, count
+}
+
+// фигура из клеток решётки со свойствами, которые подставляются
+// в шаблон имени файла
+type gridShape struct {
+	g     Grid
+	cells []Cell
+}
+
+func (s gridShape) Len() int {
+	return len(s.cells)
+}
+
+// группа симметрий, как у фигур из треугольников: первая половина
+// преобразований решётки - повороты, вторая - отражения
+func (s gridShape) symmetryGroup() string {
+	var rotations, reflections int
+	own := slices.Clone(s.cells)
+	slices.SortFunc(own, compareCells)
+	s.g.Normalize(own)
+	key := cellsKey(own)
+	transformed := make([]Cell, len(s.cells))
+	for k := 0; k < s.g.NumSymmetries(); k++ {
+		for i := 0; i < len(s.cells); i++ {
+			transformed[i] = s.g.Transform(s.cells[i], k)
+		}
+		slices.SortFunc(transformed, compareCells)
+		s.g.Normalize(transformed)
+		if cellsKey(transformed) != key {
+			continue
+		}
+		if k < s.g.NumSymmetries()/2 {
+			rotations++
+		} else {
+			reflections++
+		}
+	}
+	if reflections > 0 {
+		return fmt.Sprintf("D%d", rotations)
+	}
+	return fmt.Sprintf("C%d", rotations)
+}
+
+// число сторон клеток на границе фигуры
+func (s gridShape) Perimeter() int {
+	perimeter := 0
+	_, count := gridPolygons(s.g, s.cells)
+	for _, n := range count {
+		if n == 1 {
+			perimeter++
+		}
+	}
+	return perimeter
+}
+
+// координаты клеток канонической формы через "_"
+func (s gridShape) Encode() string {
+	canonical, _ := canonicalCells(s.g, s.cells)
+	parts := make([]string, len(canonical))
+	for i := 0; i < len(canonical); i++ {
+		parts[i] = fmt.Sprintf("%d,%d,%d", canonical[i][0], canonical[i][1], canonical[i][2])
+	}
+	return strings.Join(parts, "_")
+}
+
+// размер холста и перевод декартовых координат в пиксели
+// для изображения фигуры решётки
+type gridView struct {
+	width, height int
+	xMid, yMid    float64
+	scale         float64
+}
+
+func newGridView(polygons [][]Point, opts RenderOptions) gridView {
+	xMin, yMin := math.Inf(1), math.Inf(1)
+	xMax, yMax := math.Inf(-1), math.Inf(-1)
+	for i := 0; i < len(polygons); i++ {
+		for j := 0; j < len(polygons[i]); j++ {
+			xMin, yMin = min(xMin, polygons[i][j].X), min(yMin, polygons[i][j].Y)
+			xMax, yMax = max(xMax, polygons[i][j].X), max(yMax, polygons[i][j].Y)
+		}
+	}
+	pimg := newPatternImage(opts)
+	padding := indent
+	if opts.Padding > 0 {
+		padding = opts.Padding
+	}
+	if opts.Size > 0 {
+		pimg.scale = (float64(opts.Size) - padding) / (max(xMax-xMin, yMax-yMin) + 2)
+	}
+	return gridView{
+		width:  max(int((xMax-xMin+2)*pimg.scale+padding), opts.MinSize),
+		height: max(int((yMax-yMin+2)*pimg.scale+padding), opts.MinSize),
+		xMid:   (xMin + xMax) / 2,
+		yMid:   (yMin + yMax) / 2,
+		scale:  pimg.scale,
+	}
+}
+
+func (v gridView) toReal(p Point) (float64, float64) {
+	return (p.X-v.xMid)*v.scale + float64(v.width)/2, float64(v.height)/2 - (p.Y-v.yMid)*v.scale
+}
+
+func gridFillColor(opts RenderOptions, i int) string {
+	if opts.CellColors {
+		return paletteColor(i)
+	}
+	if opts.FillColor != "" {
+		return opts.FillColor
+	}
+	return opts.UpColor
+}
+
+// рисует фигуру из клеток решётки: заливка, тонкие стороны клеток
+// и жирная граница фигуры
+func renderGridPattern(g Grid, cells []Cell, opts RenderOptions) *gg.Context {
+	var poly []Point
+	var x, y float64
+	polygons, count := gridPolygons(g, cells)
+	view := newGridView(polygons, opts)
+	dc := gg.NewContext(view.width, view.height)
+	if !opts.Transparent {
+		dc.SetHexColor(opts.theme().background)
+		dc.Clear()
+	}
+	if !opts.SharpCorners {
+		dc.SetLineCapRound()
+		dc.SetLineJoinRound()
+	}
+
+	for i := 0; i < len(polygons); i++ {
+		dc.SetHexColor(gridFillColor(opts, i))
+		for j := 0; j < len(polygons[i]); j++ {
+			x, y = view.toReal(polygons[i][j])
+			dc.LineTo(x, y)
+		}
+		dc.ClosePath()
+		dc.Fill()
+	}
+
+	_, edgeWidth, boundaryWidth := opts.lineWidths()
+	dc.SetHexColor(opts.theme().edge)
+	for i := 0; i < len(polygons); i++ {
+		poly = polygons[i]
+		for j := 0; j < len(poly); j++ {
+			if count[edgeKey(poly[j], poly[(j+1)%len(poly)])] == 1 {
+				dc.SetLineWidth(boundaryWidth)
+			} else {
+				dc.SetLineWidth(edgeWidth)
+			}
+			x, y = view.toReal(poly[j])
+			dc.MoveTo(x, y)
+			x, y = view.toReal(poly[(j+1)%len(poly)])
+			dc.LineTo(x, y)
+			dc.Stroke()
+		}
+	}
+	return dc
+}
+
+// WriteGridPatternSVG записывает ту же картинку, что и PNG, в формате SVG
+func WriteGridPatternSVG(w io.Writer, g Grid, cells []Cell, opts RenderOptions) error {
+	var sb strings.Builder
+	var poly []Point
+	var x, y, x1, y1, x2, y2, width float64
+	polygons, count := gridPolygons(g, cells)
+	view := newGridView(polygons, opts)
+	linecap := "round"
+	if opts.SharpCorners {
+		linecap = "butt"
+	}
+
+	fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		view.width, view.height, view.width, view.height)
+	if !opts.Transparent {
+		fmt.Fprintf(&sb, "<rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n", opts.theme().background)
+	}
+	for i := 0; i < len(polygons); i++ {
+		sb.WriteString("<polygon points=\"")
+		for j := 0; j < len(polygons[i]); j++ {
+			x, y = view.toReal(polygons[i][j])
+			fmt.Fprintf(&sb, "%.2f,%.2f ", x, y)
+		}
+		fmt.Fprintf(&sb, "\" fill=\"%s\"/>\n", gridFillColor(opts, i))
+	}
+
+	_, edgeWidth, boundaryWidth := opts.lineWidths()
+	edgeColor := opts.theme().edge
+	for i := 0; i < len(polygons); i++ {
+		poly = polygons[i]
+		for j := 0; j < len(poly); j++ {
+			width = edgeWidth
+			if count[edgeKey(poly[j], poly[(j+1)%len(poly)])] == 1 {
+				width = boundaryWidth
+			}
+			x1, y1 = view.toReal(poly[j])
+			x2, y2 = view.toReal(poly[(j+1)%len(poly)])
+			fmt.Fprintf(&sb, "<line x1=\"%.2f\" y1=\"%.2f\" x2=\"%.2f\" y2=\"%.2f\" stroke=\"%s\" stroke-width=\"%g\" stroke-linecap=\"%s\"/>\n",
+				x1, y1, x2, y2, edgeColor, width, linecap)
+		}
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func saveGridPattern(g Grid, cells []Cell, path string, opts RenderOptions) error {
+	// шаблон имени может добавить подкаталоги
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	if opts.extension() != "svg" {
+		return renderGridPattern(g, cells, opts).SavePNG(path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteGridPatternSVG(f, g, cells, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SaveGridPatterns сохраняет фигуры решётки g в dir в формате и под
+// именами из opts, в jobs потоков
+func SaveGridPatterns(dir string, g Grid, patterns [][]Cell, opts RenderOptions, jobs int) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	errs := forEachParallel(len(patterns), jobs, func(i int) error {
+		name := opts.filename(i, gridShape{g: g, cells: patterns[i]})
+		err := saveGridPattern(g, patterns[i], filepath.Join(dir, name), opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package polyiamond
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGenerateGridCounts(t *testing.T) {
+	var ps [][]Cell
+	cases := []struct {
+		grid   Grid
+		counts []int
+	}{
+		// свободные полимино
+		{squareGrid{}, []int{1, 1, 2, 5, 12, 35, 108}},
+		// свободные полигексы
+		{hexGrid{}, []int{1, 1, 3, 7, 22, 82}},
+	}
+	for i := 0; i < len(cases); i++ {
+		for n := 1; n <= len(cases[i].counts); n++ {
+			ps = GenerateGrid(context.Background(), cases[i].grid, n)
+			if len(ps) != cases[i].counts[n-1] {
+				t.Errorf("%T, %d клеток: фигур %d, ожидалось %d", cases[i].grid, n, len(ps), cases[i].counts[n-1])
+			}
+		}
+	}
+}
+
+func TestGridSymmetryGroup(t *testing.T) {
+	cases := []struct {
+		grid  Grid
+		cells []Cell
+		group string
+	}{
+		{squareGrid{}, []Cell{{0, 0, 0}}, "D4"},
+		{squareGrid{}, []Cell{{0, 0, 0}, {1, 0, 0}}, "D2"},
+		{squareGrid{}, []Cell{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}, "D1"},
+		{squareGrid{}, []Cell{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {1, 1, 0}}, "D4"},
+		// S-тетрамино
+		{squareGrid{}, []Cell{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {2, 1, 0}}, "C2"},
+		// F-пентамино
+		{squareGrid{}, []Cell{{1, 0, 0}, {2, 0, 0}, {0, 1, 0}, {1, 1, 0}, {1, 2, 0}}, "C1"},
+		{hexGrid{}, []Cell{{0, 0, 0}}, "D6"},
+		{hexGrid{}, []Cell{{0, 0, 0}, {1, -1, 0}}, "D2"},
+		{hexGrid{}, []Cell{{0, 0, 0}, {1, -1, 0}, {1, 0, -1}}, "D3"},
+		{hexGrid{}, []Cell{{0, 0, 0}, {1, -1, 0}, {2, -2, 0}}, "D2"},
+		// изогнутая тройка
+		{hexGrid{}, []Cell{{0, 0, 0}, {1, -1, 0}, {2, -1, -1}}, "D1"},
+	}
+	for i := 0; i < len(cases); i++ {
+		group := gridShape{g: cases[i].grid, cells: cases[i].cells}.symmetryGroup()
+		if group != cases[i].group {
+			t.Errorf("%T %v: группа %s, ожидалась %s", cases[i].grid, cases[i].cells, group, cases[i].group)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CompatibleGroup - набор фигур, которые вместе, каждая ровно по одному
+// разу, покрывают область, как в классических головоломках из гексиамондов
+type CompatibleGroup struct {
+	Region string
+	// номера фигур в списке и их краткие записи
+	Pieces []int
+	Codes  []string
+	// одно из покрытий области этими фигурами
+	tiling []*Pattern
+}
+
+// StandardRegions возвращает шестиугольники, треугольники и параллелограммы
+// площадью не больше maxArea, площадь которых кратна pieceArea
+func StandardRegions(pieceArea, maxArea int) []string {
+	var regions []string
+	if pieceArea < 1 {
+		return nil
+	}
+	for side := 1; 6*side*side <= maxArea; side++ {
+		if 6*side*side%pieceArea == 0 {
+			regions = append(regions, fmt.Sprintf("hexagon:%d", side))
+		}
+	}
+	for side := 1; side*side <= maxArea; side++ {
+		if side*side%pieceArea == 0 {
+			regions = append(regions, fmt.Sprintf("triangle:%d", side))
+		}
+	}
+	// параллелограммы a×b и b×a совпадают с точностью до отражения
+	for width := 1; 2*width*width <= maxArea; width++ {
+		for height := width; 2*width*height <= maxArea; height++ {
+			if 2*width*height%pieceArea == 0 {
+				regions = append(regions, fmt.Sprintf("parallelogram:%dx%d", width, height))
+			}
+		}
+	}
+	return regions
+}
+
+// FindCompatibleGroups ищет наборы фигур из pieces, которые вместе покрывают
+// область regionSpec, и возвращает их по одному покрытию на набор в порядке
+// номеров фигур. Перебор покрытий всеми фигурами сразу делится в workers
+// потоков по фигуре, покрывающей первую клетку области. При отмене ctx
+// список неполный.
+func FindCompatibleGroups(ctx context.Context, regionSpec string, pieces []*Pattern, workers int) ([]CompatibleGroup, error) {
+	var groups []CompatibleGroup
+	var branches [][2]int
+	region, err := ParseRegion(regionSpec)
+	if err != nil {
+		return nil, err
+	}
+	variants := make([][]pieceVariant, len(pieces))
+	for i := 0; i < len(pieces); i++ {
+		variants[i] = newPieceVariants(pieces[i])
+	}
+	for i := 0; i < len(variants); i++ {
+		for j := 0; j < len(variants[i]); j++ {
+			branches = append(branches, [2]int{i, j})
+		}
+	}
+	// если площадь области равна площади всех фигур, набор может быть
+	// только один, и в каждой ветви достаточно первого покрытия
+	limit, area := 0, 0
+	for i := 0; i < len(pieces); i++ {
+		area += pieces[i].Len()
+	}
+	if area == region.Len() {
+		limit = 1
+	}
+	solvers := make([]*tilingSolver, len(branches))
+	forEachParallel(len(branches), workers, func(b int) error {
+		s := newTilingSolver(ctx, region, variants, false, limit)
+		s.distinct = true
+		s.seenSets = make(map[string]bool)
+		if s.place(&s.cells[0], branches[b][0], variants[branches[b][0]][branches[b][1]]) {
+			s.search(1)
+		}
+		solvers[b] = s
+		return nil
+	})
+	seen := make(map[string]bool)
+	for b := 0; b < len(solvers); b++ {
+		for k := 0; k < len(solvers[b].solutions); k++ {
+			pieceSet := solvers[b].pieceSets[k]
+			key := fmt.Sprint(pieceSet)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			codes := make([]string, len(pieceSet))
+			for j := 0; j < len(codes); j++ {
+				codes[j] = pieces[pieceSet[j]].Encode()
+			}
+			groups = append(groups, CompatibleGroup{Region: regionSpec, Pieces: pieceSet, Codes: codes, tiling: solvers[b].solutions[k]})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return slices.Compare(groups[i].Pieces, groups[j].Pieces) < 0
+	})
+	return groups, nil
+}
+
+// GroupRegions разбирает список областей через запятую; standard означает
+// все стандартные области, площадь которых не больше площади всех фигур
+// и кратна площади каждой из них
+func GroupRegions(spec string, pieces []*Pattern) []string {
+	var regions []string
+	if spec == "standard" {
+		pieceArea, totalArea := 0, 0
+		for i := 0; i < len(pieces); i++ {
+			pieceArea = gcd(pieceArea, pieces[i].Len())
+			totalArea += pieces[i].Len()
+		}
+		return StandardRegions(pieceArea, totalArea)
+	}
+	regions = strings.Split(spec, ",")
+	for i := 0; i < len(regions); i++ {
+		regions[i] = strings.TrimSpace(regions[i])
+	}
+	return regions
+}
+
+// SaveCompatibleGroups ищет наборы фигур, покрывающие область regionSpec,
+// и сохраняет в dir по одному покрытию каждого набора
+// как <форма>_<стороны>_<номер>.png
+func SaveCompatibleGroups(ctx context.Context, regionSpec string, pieces []*Pattern, dir string, workers int, opts RenderOptions) ([]CompatibleGroup, error) {
+	var name string
+	region, err := ParseRegion(regionSpec)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := FindCompatibleGroups(ctx, regionSpec, pieces, workers)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = "."
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(groups); i++ {
+		name = fmt.Sprintf("%s_%d.png", strings.ReplaceAll(regionSpec, ":", "_"), i)
+		pimg := renderTiling(region, groups[i].tiling, opts)
+		err = pimg.saveAsPNG(filepath.Join(dir, name))
+		if err != nil {
+			return groups[:i], err
+		}
+	}
+	return groups, nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
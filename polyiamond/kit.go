@@ -0,0 +1,102 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KitPart - часть набора для головоломки: count фигур из size треугольников
+type KitPart struct {
+	Count int
+	Size  int
+}
+
+// ParseKit разбирает описание набора вида "4x4,6x6,2x8": через запятую
+// число фигур и число треугольников в каждой. Части одного размера
+// складываются; части возвращаются по возрастанию размера.
+func ParseKit(s string) ([]KitPart, error) {
+	var parts []KitPart
+	var count, size int
+	var err error
+	counts := make(map[int]int)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.ReplaceAll(strings.TrimSpace(field), "×", "x")
+		countSize := strings.SplitN(field, "x", 2)
+		if len(countSize) != 2 {
+			return nil, fmt.Errorf("неверная часть набора %q: нужно число фигур x число треугольников", field)
+		}
+		count, err = strconv.Atoi(countSize[0])
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("неверное число фигур в части набора %q", field)
+		}
+		size, err = strconv.Atoi(countSize[1])
+		if err != nil || size < MinNumTriangles || size > MaxNumTriangles {
+			return nil, fmt.Errorf("число треугольников в части набора %q должно быть от %d до %d", field, MinNumTriangles, MaxNumTriangles)
+		}
+		counts[size] += count
+	}
+	for size, count := range counts {
+		parts = append(parts, KitPart{Count: count, Size: size})
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Size < parts[j].Size
+	})
+	return parts, nil
+}
+
+// GenerateKit возвращает по коллекции на каждую часть набора: первые
+// Count фигур из Size треугольников в порядке номеров полного перебора;
+// если различных фигур меньше, они повторяются по кругу
+func GenerateKit(ctx context.Context, parts []KitPart, workers int) ([]*Collection, error) {
+	var all, pc *Collection
+	kit := make([]*Collection, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		all = GenerateRange(ctx, parts[i].Size, parts[i].Size, NewPattern(), false, false, nil, workers, "", nil, nil)[0]
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pc = NewCollection()
+		for j := 0; j < parts[i].Count; j++ {
+			pc.patterns = append(pc.patterns, all.patterns[j%len(all.patterns)])
+		}
+		kit = append(kit, pc)
+	}
+	return kit, nil
+}
+
+// SaveKit рисует набор фигур по описанию spec в одном масштабе на холстах
+// одного размера: фигуры каждого размера сохраняются в dir/<размер>,
+// а весь набор - на одном листе dir/kit.png. Возвращает число фигур.
+func SaveKit(ctx context.Context, spec, dir string, opts RenderOptions, jobs int) (int, error) {
+	var all []*Pattern
+	parts, err := ParseKit(spec)
+	if err != nil {
+		return 0, err
+	}
+	kit, err := GenerateKit(ctx, parts, jobs)
+	if err != nil {
+		return 0, err
+	}
+	if dir == "" {
+		dir = "."
+	}
+	opts.ViewRadius = max(opts.ViewRadius, CommonViewRadius(kit))
+	for i := 0; i < len(kit); i++ {
+		_, errs := SavePatterns(filepath.Join(dir, fmt.Sprint(parts[i].Size)), kit[i], opts, jobs, nil)
+		if len(errs) > 0 {
+			return 0, errs[0]
+		}
+		all = append(all, kit[i].patterns...)
+	}
+	columns := int(math.Ceil(math.Sqrt(float64(len(all)))))
+	err = renderMontage(all, opts, columns).SavePNG(filepath.Join(dir, "kit.png"))
+	if err != nil {
+		return 0, err
+	}
+	return len(all), nil
+}
@@ -0,0 +1,62 @@
+package polyiamond
+
+import "fmt"
+
+// индексирует канонические формы фигур коллекции, если она получена
+// не через appendUnique: загружена из файла или найдена перебором
+// Редельмейера, который не ведёт общего набора; при -placements
+// в наборе записаны положения, а не канонические формы
+func (pc *Collection) indexCanonical() {
+	pc.buckets = nil
+	if !pc.placements && len(pc.hashes) == len(pc.patterns) {
+		return
+	}
+	pc.hashes = make(map[string]bool, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		pc.hashes[pc.patterns[i].getCanonical()] = true
+	}
+}
+
+// Merge добавляет в коллекцию фигуры other, которых в ней ещё нет
+// с точностью до поворотов, отражений и сдвигов, и возвращает число
+// добавленных. Так объединяются результаты, полученные по частям
+// на разных машинах или сохранённые в нескольких файлах.
+func (pc *Collection) Merge(other *Collection) int {
+	added := 0
+	pc.indexCanonical()
+	for i := 0; i < len(other.patterns); i++ {
+		if pc.appendCanonical(other.patterns[i], other.patterns[i].getCanonical()) {
+			added++
+		}
+	}
+	return added
+}
+
+// MergeFiles объединяет фигуры из файлов в формате, который понимает -load,
+// и сохраняет их без повторов в JSON по пути out; возвращает число
+// сохранённых фигур и число отброшенных повторов
+func MergeFiles(paths []string, out string) (int, int, error) {
+	var pc *Collection
+	var err error
+	total, added := 0, 0
+	merged := NewCollection()
+	for i := 0; i < len(paths); i++ {
+		pc, err = loadPatterns(paths[i])
+		if err != nil {
+			return 0, 0, err
+		}
+		for j := 0; j < len(pc.patterns); j++ {
+			err = validatePattern(pc.patterns[j])
+			if err != nil {
+				return 0, 0, fmt.Errorf("%s: фигура %d: %v", paths[i], j, err)
+			}
+		}
+		total += len(pc.patterns)
+		added += merged.Merge(pc)
+	}
+	err = merged.SaveJSON(out)
+	if err != nil {
+		return 0, 0, err
+	}
+	return added, total - added, nil
+}
@@ -0,0 +1,63 @@
+package polyiamond
+
+import (
+	"fmt"
+	"io"
+)
+
+// A000577 в OEIS: число фигур из n треугольников с точностью до поворотов
+// и отражений, начиная с n = 1
+var polyiamondCounts = []int{
+	1, 1, 1, 3, 4, 12, 24, 66, 160, 448, 1186, 3334, 9235, 26166, 73983, 211297,
+}
+
+// KnownCount возвращает число фигур из numTriangles треугольников по OEIS
+func KnownCount(numTriangles int) (int, bool) {
+	if numTriangles < 1 || numTriangles > len(polyiamondCounts) {
+		return 0, false
+	}
+	return polyiamondCounts[numTriangles-1], true
+}
+
+// WriteCountTable печатает число фигур каждого размера, начиная с minTriangles.
+// При check добавляет значения A000577 и отмечает несовпадения;
+// возвращает false, если хотя бы одно значение не совпало.
+func WriteCountTable(w io.Writer, collections []*Collection, minTriangles int, check bool) (bool, error) {
+	counts := make([]int, len(collections))
+	for i := 0; i < len(collections); i++ {
+		counts[i] = len(collections[i].patterns)
+	}
+	return WriteCounts(w, counts, minTriangles, check)
+}
+
+// WriteCounts - то же, что WriteCountTable, для уже подсчитанных фигур
+func WriteCounts(w io.Writer, counts []int, minTriangles int, check bool) (bool, error) {
+	var err error
+	ok := true
+	if check {
+		_, err = fmt.Fprintf(w, "%4s %12s %12s\n", "n", "фигур", "A000577")
+	} else {
+		_, err = fmt.Fprintf(w, "%4s %12s\n", "n", "фигур")
+	}
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < len(counts); i++ {
+		n := minTriangles + i
+		count := counts[i]
+		if !check {
+			_, err = fmt.Fprintf(w, "%4d %12d\n", n, count)
+		} else if known, found := KnownCount(n); !found {
+			_, err = fmt.Fprintf(w, "%4d %12d %12s\n", n, count, "-")
+		} else if known != count {
+			ok = false
+			_, err = fmt.Fprintf(w, "%4d %12d %12d  не совпадает\n", n, count, known)
+		} else {
+			_, err = fmt.Fprintf(w, "%4d %12d %12d\n", n, count, known)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return ok, nil
+}
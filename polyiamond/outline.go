@@ -0,0 +1,149 @@
+package polyiamond
+
+import "math"
+
+// Point - вершина контура в декартовых координатах изображения,
+// длина стороны треугольника равна 1
+type Point struct {
+	X, Y float64
+}
+
+// направленная сторона контура: фигура лежит слева от неё
+type outlineEdge struct {
+	from, to [2]float64
+}
+
+// вершины сравниваются после округления, координаты у них иррациональные
+func vertexKey(v [2]float64) [2]int64 {
+	return [2]int64{int64(math.Round(v[0] * 1e6)), int64(math.Round(v[1] * 1e6))}
+}
+
+func (p *Pattern) outlineEdges() []outlineEdge {
+	var e outlineEdge
+	var t *Triangle
+	var cx, cy float64
+	edges := make([]outlineEdge, 0, len(p.triangles)*3)
+	for i := 0; i < len(p.triangles); i++ {
+		t = &p.triangles[i]
+		cx, cy = t.getCenter()
+		for axis := 1; axis <= 3; axis++ {
+			if p.contains(t.getNeighbour(axis)) {
+				continue
+			}
+			e.from[0], e.from[1], e.to[0], e.to[1] = t.getCartesianCoords(axis)
+			if (e.to[0]-e.from[0])*(cy-e.from[1])-(e.to[1]-e.from[1])*(cx-e.from[0]) < 0 {
+				e.from, e.to = e.to, e.from
+			}
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// замкнутые контуры фигуры: внешний обходится против часовой стрелки,
+// контуры дыр - по часовой. В вершине, где фигура касается себя, контур
+// поворачивает как можно правее, оставаясь на границе той же пустой
+// области, поэтому у фигуры ровно один внешний контур и по контуру на
+// каждую дыру, даже если дыра касается внешней границы. Вершины лежащих
+// на одной прямой сторон выбрасываются.
+func (p *Pattern) outlineLoops() [][][2]float64 {
+	var loop [][2]float64
+	var cur, next int
+	var best, angle, dx, dy, ndx, ndy float64
+	edges := p.outlineEdges()
+	outgoing := make(map[[2]int64][]int, len(edges))
+	for i := 0; i < len(edges); i++ {
+		outgoing[vertexKey(edges[i].from)] = append(outgoing[vertexKey(edges[i].from)], i)
+	}
+	used := make([]bool, len(edges))
+	loops := make([][][2]float64, 0, 1)
+	for start := 0; start < len(edges); start++ {
+		if used[start] {
+			continue
+		}
+		loop = nil
+		cur = start
+		for !used[cur] {
+			used[cur] = true
+			loop = append(loop, edges[cur].from)
+			dx, dy = edges[cur].to[0]-edges[cur].from[0], edges[cur].to[1]-edges[cur].from[1]
+			next = -1
+			candidates := outgoing[vertexKey(edges[cur].to)]
+			for j := 0; j < len(candidates); j++ {
+				ndx = edges[candidates[j]].to[0] - edges[candidates[j]].from[0]
+				ndy = edges[candidates[j]].to[1] - edges[candidates[j]].from[1]
+				angle = math.Atan2(dx*ndy-dy*ndx, dx*ndx+dy*ndy)
+				if next < 0 || angle < best {
+					next, best = candidates[j], angle
+				}
+			}
+			if next < 0 {
+				break
+			}
+			cur = next
+		}
+		loops = append(loops, simplifyLoop(loop))
+	}
+	return loops
+}
+
+func simplifyLoop(loop [][2]float64) [][2]float64 {
+	var prev, v, next [2]float64
+	simplified := make([][2]float64, 0, len(loop))
+	for i := 0; i < len(loop); i++ {
+		prev = loop[(i+len(loop)-1)%len(loop)]
+		v = loop[i]
+		next = loop[(i+1)%len(loop)]
+		if math.Abs((v[0]-prev[0])*(next[1]-v[1])-(v[1]-prev[1])*(next[0]-v[0])) > 1e-9 {
+			simplified = append(simplified, v)
+		}
+	}
+	return simplified
+}
+
+// удвоенная площадь со знаком: положительна при обходе против часовой стрелки
+func loopArea2(loop [][2]float64) float64 {
+	var j int
+	area := 0.0
+	for i := 0; i < len(loop); i++ {
+		j = (i + 1) % len(loop)
+		area += loop[i][0]*loop[j][1] - loop[j][0]*loop[i][1]
+	}
+	return area
+}
+
+func toPoints(loop [][2]float64) []Point {
+	points := make([]Point, len(loop))
+	for i := 0; i < len(loop); i++ {
+		points[i] = Point{X: loop[i][0], Y: loop[i][1]}
+	}
+	return points
+}
+
+// внешний контур и контуры дыр
+func (p *Pattern) outline() ([]Point, [][]Point) {
+	var outer []Point
+	var holes [][]Point
+	loops := p.outlineLoops()
+	for i := 0; i < len(loops); i++ {
+		if loopArea2(loops[i]) > 0 {
+			outer = toPoints(loops[i])
+		} else {
+			holes = append(holes, toPoints(loops[i]))
+		}
+	}
+	return outer, holes
+}
+
+// Boundary возвращает вершины внешней границы фигуры по порядку обхода
+// против часовой стрелки; вершины есть только в углах границы
+func (p *Pattern) Boundary() []Point {
+	outer, _ := p.outline()
+	return outer
+}
+
+// Holes возвращает границы дыр фигуры, каждая обходится по часовой стрелке
+func (p *Pattern) Holes() [][]Point {
+	_, holes := p.outline()
+	return holes
+}
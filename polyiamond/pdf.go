@@ -0,0 +1,178 @@
+package polyiamond
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// каталог в PDF: страницы A4 в пунктах, на каждой сетка из
+// pdfColumns x pdfRows фигур с подписями под ними
+const pdfPageWidth = 595.0
+const pdfPageHeight = 842.0
+const pdfMargin = 36.0
+const pdfColumns = 4
+const pdfRows = 6
+const pdfFontSize = 8.0
+const pdfCaptionHeight = 14.0
+
+// ширина символов встроенного шрифта Helvetica в тысячных долях кегля;
+// в подписях встречаются только цифры, буквы групп симметрии и знаки
+var helveticaWidths = map[rune]int{
+	' ': 278, '=': 584, 'C': 722, 'D': 722, 'P': 667,
+}
+
+func helveticaTextWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		if w, ok := helveticaWidths[r]; ok {
+			width += w
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// PDF собирается вручную: объекты нумеруются заранее, чтобы список
+// страниц можно было записать до самих страниц
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (pw *pdfWriter) object(body string) {
+	pw.offsets = append(pw.offsets, pw.buf.Len())
+	fmt.Fprintf(&pw.buf, "%d 0 obj\n%s\nendobj\n", len(pw.offsets), body)
+}
+
+func (pw *pdfWriter) stream(content string) {
+	pw.object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+}
+
+func pdfColor(hex string) string {
+	var r, g, b uint64
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) == 6 {
+		r, _ = strconv.ParseUint(hex[0:2], 16, 8)
+		g, _ = strconv.ParseUint(hex[2:4], 16, 8)
+		b, _ = strconv.ParseUint(hex[4:6], 16, 8)
+	}
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// фигура p в клетке с левым нижним углом (x, y) и стороной cell;
+// ptScale - пунктов на длину стороны треугольника
+func writePDFPattern(sb *strings.Builder, p *Pattern, index int, x, y, cell, ptScale float64, opts RenderOptions) {
+	var v [3][2]float64
+	var px, py, x1, y1, x2, y2, width float64
+	x1, y1, x2, y2 = p.cartesianBounds()
+	cx := x + cell/2 - (x1+x2)/2*ptScale
+	cy := y + pdfCaptionHeight + (cell-pdfCaptionHeight)/2 - (y1+y2)/2*ptScale
+
+	fmt.Fprintf(sb, "0.8 G 0.3 w %.2f %.2f %.2f %.2f re S\n", x, y, cell, cell)
+	if opts.isFilled() {
+		for i := 0; i < len(p.triangles); i++ {
+			v = p.triangles[i].vertices()
+			fmt.Fprintf(sb, "%s rg", pdfColor(opts.fillColor(p, i)))
+			for j := 0; j < len(v); j++ {
+				px, py = cx+v[j][0]*ptScale, cy+v[j][1]*ptScale
+				if j == 0 {
+					fmt.Fprintf(sb, " %.2f %.2f m", px, py)
+				} else {
+					fmt.Fprintf(sb, " %.2f %.2f l", px, py)
+				}
+			}
+			sb.WriteString(" h f\n")
+		}
+	}
+
+	_, edgeWidth, boundaryWidth := opts.lineWidths()
+	lines := p.edgeLines()
+	sb.WriteString("0 G\n")
+	for i := 0; i < len(lines); i++ {
+		width = edgeWidth
+		if lines[i].bold {
+			width = boundaryWidth
+		}
+		// толщины линий заданы в пикселях при масштабе по умолчанию
+		fmt.Fprintf(sb, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width*ptScale/scale,
+			cx+lines[i].x1*ptScale, cy+lines[i].y1*ptScale, cx+lines[i].x2*ptScale, cy+lines[i].y2*ptScale)
+	}
+
+	caption := fmt.Sprintf("%d  %s  P=%d", index, p.symmetryGroup(), p.Perimeter())
+	fmt.Fprintf(sb, "0 g BT /F1 %g Tf %.2f %.2f Td (%s) Tj ET\n", pdfFontSize,
+		x+(cell-helveticaTextWidth(caption, pdfFontSize))/2, y+(pdfCaptionHeight-pdfFontSize)/2+1, caption)
+}
+
+// WriteCatalogPDF записывает фигуры постранично, по сетке на страницу;
+// под каждой фигурой - номер, группа симметрии и периметр.
+// Все фигуры рисуются в одном масштабе.
+func WriteCatalogPDF(w io.Writer, ps []*Pattern, opts RenderOptions) error {
+	var pw pdfWriter
+	var sb strings.Builder
+	var x, y float64
+	perPage := pdfColumns * pdfRows
+	numPages := max((len(ps)+perPage-1)/perPage, 1)
+	cell := min((pdfPageWidth-2*pdfMargin)/pdfColumns, (pdfPageHeight-2*pdfMargin)/pdfRows)
+	radius := 0.0
+	for i := 0; i < len(ps); i++ {
+		radius = max(radius, ps[i].cartesianRadius())
+	}
+	// фигура с полем в половину стороны треугольника помещается в клетку над подписью
+	ptScale := (cell - pdfCaptionHeight) / (2*radius + 1)
+	linecap := 1
+	if opts.SharpCorners {
+		linecap = 0
+	}
+
+	pw.buf.WriteString("%PDF-1.4\n")
+	// 1 - каталог, 2 - список страниц, 3 - шрифт, далее по два объекта
+	// на страницу: сама страница и её содержимое
+	pw.object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, numPages)
+	for i := 0; i < numPages; i++ {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	pw.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), numPages))
+	pw.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	for page := 0; page < numPages; page++ {
+		pw.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*page))
+		sb.Reset()
+		fmt.Fprintf(&sb, "%d J %d j\n", linecap, linecap)
+		for i := page * perPage; i < len(ps) && i < (page+1)*perPage; i++ {
+			x = pdfMargin + float64((i-page*perPage)%pdfColumns)*cell
+			y = pdfPageHeight - pdfMargin - float64((i-page*perPage)/pdfColumns+1)*cell
+			writePDFPattern(&sb, ps[i], i, x, y, cell, ptScale, opts)
+		}
+		pw.stream(sb.String())
+	}
+
+	xref := pw.buf.Len()
+	fmt.Fprintf(&pw.buf, "xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1)
+	for i := 0; i < len(pw.offsets); i++ {
+		fmt.Fprintf(&pw.buf, "%010d 00000 n \n", pw.offsets[i])
+	}
+	fmt.Fprintf(&pw.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, xref)
+	_, err := w.Write(pw.buf.Bytes())
+	return err
+}
+
+func (pc *Collection) SaveCatalogPDF(path string, opts RenderOptions) error {
+	if len(pc.patterns) == 0 {
+		return fmt.Errorf("нет фигур для %s", path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteCatalogPDF(f, pc.patterns, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,2913 @@
+// Пакет polyiamond перечисляет фигуры из одинаковых правильных
+// треугольников, соединённых сторонами, с точностью до поворотов,
+// отражений и сдвигов, и рисует их.
+package polyiamond
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+const MinNumTriangles = 4
+
+// наибольшее число треугольников, при котором все найденные фигуры
+// держатся в памяти; фигуры побольше лучше сразу записывать на диск
+const MaxNumTriangles = 16
+const tg30 = 0.57735026918962576450914878050196
+const tg30x2 = 1.1547005383792515290182975610039
+const scale = 200.0
+const indent = 20.0
+const progressInterval = 10000
+const montageCellSize = 200
+
+// предел модуля координат: повороты, отражения и сдвиги фигуры
+// в пределах этой величины заведомо не переполняют int
+const maxCoord = 1 << 20
+const maxRegionCells = 10000
+
+type Triangle struct {
+	x int
+	y int
+	z int
+}
+
+func newTriangle(x, y, z int) *Triangle {
+	return &Triangle{
+		x: x,
+		y: y,
+		z: z,
+	}
+}
+
+func isSafeCoord(v int) bool {
+	return v >= -maxCoord && v <= maxCoord
+}
+
+func NewTriangle(x, y, z int) (*Triangle, error) {
+	if !isSafeCoord(x) || !isSafeCoord(y) || !isSafeCoord(z) {
+		return nil, fmt.Errorf("треугольник %d,%d,%d: координаты по модулю не должны превышать %d", x, y, z, maxCoord)
+	}
+	if sum := x + y + z; sum != 1 && sum != -1 {
+		return nil, fmt.Errorf("треугольник %d,%d,%d не лежит на сетке: сумма координат должна быть 1 или -1", x, y, z)
+	}
+	return newTriangle(x, y, z), nil
+}
+
+func (t *Triangle) Coords() (int, int, int) {
+	return t.x, t.y, t.z
+}
+
+// треугольник упаковывается в число: x и y со сдвигом в неотрицательную
+// область и направление; z восстанавливается по сумме координат.
+// Сдвига хватает с запасом для координат до maxCoord и их выравнивания.
+const packOffset = 1 << 23
+
+func (t *Triangle) pack() uint64 {
+	v := uint64(t.x+packOffset)<<25 | uint64(t.y+packOffset)<<1
+	if t.isUpward() {
+		v |= 1
+	}
+	return v
+}
+
+func unpackTriangle(v uint64) *Triangle {
+	x := int(v>>25) - packOffset
+	y := int(v>>1&(1<<24-1)) - packOffset
+	sum := -1
+	if v&1 == 1 {
+		sum = 1
+	}
+	return newTriangle(x, y, sum-x-y)
+}
+
+func (t *Triangle) getCopy() *Triangle {
+	return newTriangle(t.x, t.y, t.z)
+}
+
+func (t *Triangle) isEqual(other *Triangle) bool {
+	return t.x == other.x && t.y == other.y && t.z == other.z
+}
+
+func (t *Triangle) isLess(other *Triangle) bool {
+	if t.x != other.x {
+		return t.x < other.x
+	}
+	if t.y != other.y {
+		return t.y < other.y
+	}
+	return t.z < other.z
+}
+
+// сетка повёрнута так, что одна из сторон вертикальна:
+// "верхние" треугольники направлены вершиной влево, "нижние" - вправо
+func (t *Triangle) isUpward() bool {
+	return t.x+t.y+t.z > 0
+}
+
+// Кубические координаты треугольной сетки (a, b, c), как в распространённых
+// библиотеках для треугольных и шестиугольных сеток: переход через сторону
+// меняет ровно одну координату на 1, а сумма a+b+c равна 2 для "верхних"
+// треугольников и 1 для "нижних".
+//
+//	a = (y+z-x+1)/2, b = (x+z-y+1)/2, c = (x+y-z+1)/2
+//	x = b+c-1,       y = a+c-1,       z = a+b-1
+func (t *Triangle) toCube() (int, int, int) {
+	return (t.y + t.z - t.x + 1) / 2, (t.x + t.z - t.y + 1) / 2, (t.x + t.y - t.z + 1) / 2
+}
+
+func triangleFromCube(a, b, c int) (*Triangle, error) {
+	if !isSafeCoord(a) || !isSafeCoord(b) || !isSafeCoord(c) {
+		return nil, fmt.Errorf("кубические координаты %d,%d,%d: модуль не должен превышать %d", a, b, c, maxCoord)
+	}
+	if sum := a + b + c; sum != 1 && sum != 2 {
+		return nil, fmt.Errorf("кубические координаты %d,%d,%d: сумма должна быть 1 или 2", a, b, c)
+	}
+	return NewTriangle(b+c-1, a+c-1, a+b-1)
+}
+
+func (t *Triangle) getCoord(axis int) int {
+	switch axis {
+	case 1:
+		return t.x
+	case 2:
+		return t.y
+	case 3:
+		return t.z
+	}
+	return 0
+}
+
+func (t *Triangle) getNeighbourCoords(axis int) (int, int, int) {
+	var look = t.x + t.y + t.z
+	switch axis {
+	case 1:
+		return t.x, t.y - look, t.z - look
+	case 2:
+		return t.x - look, t.y, t.z - look
+	case 3:
+		return t.x - look, t.y - look, t.z
+	}
+	return 0, 0, 0
+}
+
+func (t *Triangle) getNeighbour(axis int) *Triangle {
+	return newTriangle(t.getNeighbourCoords(axis))
+}
+
+func (t *Triangle) getVertexNeighbours() []*Triangle {
+	var look = t.x + t.y + t.z
+	return []*Triangle{
+		newTriangle(t.x+1, t.y-1, t.z),
+		newTriangle(t.x-1, t.y+1, t.z),
+		newTriangle(t.x, t.y+1, t.z-1),
+		newTriangle(t.x, t.y-1, t.z+1),
+		newTriangle(t.x+1, t.y, t.z-1),
+		newTriangle(t.x-1, t.y, t.z+1),
+		newTriangle(t.x-2*look, t.y, t.z),
+		newTriangle(t.x, t.y-2*look, t.z),
+		newTriangle(t.x, t.y, t.z-2*look),
+	}
+}
+
+func (t *Triangle) getRotatedCoords(angle int) (int, int, int) {
+	switch angle {
+	case 1:
+		return -t.y, -t.z, -t.x
+	case 2:
+		return t.z, t.x, t.y
+	case 3:
+		return -t.x, -t.y, -t.z
+	case 4:
+		return t.y, t.z, t.x
+	case 5:
+		return -t.z, -t.x, -t.y
+	}
+	return t.x, t.y, t.z
+}
+
+func (t *Triangle) getRotated(angle int) *Triangle {
+	return newTriangle(t.getRotatedCoords(angle))
+}
+
+func (t *Triangle) getReflectedCoords(axis int) (int, int, int) {
+	switch axis {
+	case 1:
+		return -t.x, -t.z, -t.y
+	case 2:
+		return -t.z, -t.y, -t.x
+	case 3:
+		return -t.y, -t.x, -t.z
+	}
+	return t.x, t.y, t.z
+}
+
+func (t *Triangle) getReflected(axis int) *Triangle {
+	return newTriangle(t.getReflectedCoords(axis))
+}
+
+func (t *Triangle) getShiftedCoords(shift, axis int) (int, int, int) {
+	switch axis {
+	case 1:
+		return t.x, t.y + shift, t.z - shift
+	case 2:
+		return t.x - shift, t.y, t.z + shift
+	case 3:
+		return t.x + shift, t.y - shift, t.z
+	}
+	return t.x, t.y, t.z
+}
+
+func (t *Triangle) getShifted(shift, axis int) *Triangle {
+	return newTriangle(t.getShiftedCoords(shift, axis))
+}
+
+func (t *Triangle) getCartesianCoords(axis int) (float64, float64, float64, float64) {
+	var x1, y1, x2, y2, xf, yf, zf float64
+	xf = float64(t.x)
+	yf = float64(t.y)
+	zf = float64(t.z)
+	switch axis {
+	case 1:
+		x1 = xf / tg30x2
+		y1 = xf/2.0 + yf
+		x2 = x1
+		y2 = -xf/2.0 - zf
+	case 2:
+		x1 = xf / tg30x2
+		y1 = xf/2.0 + yf
+		x2 = -(zf + yf) / tg30x2
+		y2 = x2*tg30 + yf
+	case 3:
+		x1 = xf / tg30x2
+		y1 = -xf/2.0 - zf
+		x2 = -(zf + yf) / tg30x2
+		y2 = -x2*tg30 - zf
+	}
+	return x1, y1, x2, y2
+}
+
+// вершины треугольника в декартовых координатах
+func (t *Triangle) vertices() [3][2]float64 {
+	var v [3][2]float64
+	v[0][0], v[0][1], v[1][0], v[1][1] = t.getCartesianCoords(1)
+	_, _, v[2][0], v[2][1] = t.getCartesianCoords(2)
+	return v
+}
+
+func (t *Triangle) getCenter() (float64, float64) {
+	v := t.vertices()
+	return (v[0][0] + v[1][0] + v[2][0]) / 3, (v[0][1] + v[1][1] + v[2][1]) / 3
+}
+
+// треугольники хранятся по значению в одном массиве: у фигуры из n
+// треугольников это одно выделение памяти вместо n+1. Копии фигуры делят
+// массив с оригиналом, пока одна из них не изменится (см. getCopy).
+type Pattern struct {
+	triangles   []Triangle
+	members     map[Triangle]bool
+	patternHash string
+	validHash   bool
+	buildSteps  []buildStep
+	// треугольник, с которого началось построение при пустой затравке:
+	// шаги повторяются от него; нулевой, если шаги не записаны
+	buildRoot Triangle
+	// хэши положений фигуры для isEqual и isEqualChiral:
+	// только повороты и повороты вместе с отражениями
+	rotationHashes map[string]bool
+	variantHashes  map[string]bool
+}
+
+// шаг построения: к треугольнику с номером index (в порядке добавления)
+// пристроен сосед по оси axis
+type buildStep struct {
+	index int
+	axis  int
+}
+
+func NewPattern() *Pattern {
+	return newPatternWithCap(MinNumTriangles)
+}
+
+func newPatternWithCap(capacity int) *Pattern {
+	return &Pattern{
+		triangles: make([]Triangle, 0, capacity),
+	}
+}
+
+// хэш фигуры - упакованные треугольники в порядке возрастания,
+// по 8 байт на треугольник; сравнение хэшей как строк совпадает
+// со сравнением упакованных чисел
+func (p *Pattern) validateHash() {
+	if p.validHash {
+		return
+	}
+	packed := make([]uint64, len(p.triangles))
+	for i := 0; i < len(p.triangles); i++ {
+		packed[i] = p.triangles[i].pack()
+	}
+	slices.Sort(packed)
+	buf := make([]byte, 8*len(packed))
+	for i := 0; i < len(packed); i++ {
+		binary.BigEndian.PutUint64(buf[8*i:], packed[i])
+	}
+	p.patternHash = string(buf)
+	p.validHash = true
+}
+
+// координаты хэша в виде "x,y,z x,y,z ..."
+func formatHash(hash string) string {
+	var t *Triangle
+	parts := make([]string, 0, len(hash)/8)
+	for i := 0; i+8 <= len(hash); i += 8 {
+		t = unpackTriangle(binary.BigEndian.Uint64([]byte(hash[i : i+8])))
+		parts = append(parts, fmt.Sprintf("%d,%d,%d", t.x, t.y, t.z))
+	}
+	return strings.Join(parts, " ")
+}
+
+// копия делит массив треугольников с оригиналом. Ёмкость среза копии
+// равна длине, поэтому addTriangle у копии выделяет новый массив, а
+// добавления в оригинал копия не видит; изменения на месте (сортировка
+// в normalizeOrder) тоже работают с новым массивом.
+func (p *Pattern) getCopy() *Pattern {
+	n := len(p.triangles)
+	pCopy := &Pattern{
+		triangles: p.triangles[:n:n],
+	}
+	pCopy.validateHash()
+	pCopy.buildIndex()
+	return pCopy
+}
+
+func (p *Pattern) getBuildSteps() []buildStep {
+	return p.buildSteps
+}
+
+// переносит на p запись построения фигуры from
+func (p *Pattern) copyBuild(from *Pattern) {
+	p.buildSteps = from.buildSteps
+	p.buildRoot = from.buildRoot
+}
+
+// повторяет построение фигуры из затравки по записанным шагам
+func replayBuild(seed *Pattern, steps []buildStep) (*Pattern, error) {
+	p := seed.getCopy()
+	for i := 0; i < len(steps); i++ {
+		if steps[i].index < 0 || steps[i].index >= p.Len() || steps[i].axis < 1 || steps[i].axis > 3 {
+			return nil, fmt.Errorf("неверный шаг построения %d", i+1)
+		}
+		p.addTriangle(p.triangles[steps[i].index].getNeighbour(steps[i].axis))
+	}
+	return p, nil
+}
+
+func (p *Pattern) normalizeOrder() {
+	triangles := slices.Clone(p.triangles)
+	sort.Slice(triangles, func(i, j int) bool {
+		return triangles[i].isLess(&triangles[j])
+	})
+	p.triangles = triangles
+	p.validHash = false
+	p.validateHash()
+}
+
+func (p *Pattern) Triangles() []Triangle {
+	return p.getSortedTriangles()
+}
+
+func (p *Pattern) Len() int {
+	return len(p.triangles)
+}
+
+func (p *Pattern) getSortedTriangles() []Triangle {
+	sorted := make([]Triangle, len(p.triangles))
+	for i := 0; i < len(p.triangles); i++ {
+		sorted[i] = p.triangles[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].isLess(&sorted[j])
+	})
+	return sorted
+}
+
+func isSameTriangles(a, b []Triangle) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Pattern) isEqual(other *Pattern) bool {
+	return p.matches(other, true)
+}
+
+func (p *Pattern) isEqualChiral(other *Pattern) bool {
+	return p.matches(other, false)
+}
+
+func (p *Pattern) isChiral() bool {
+	return !p.isEqualChiral(p.getReflected(3))
+}
+
+// хэши всех положений фигуры, выровненных по оси 3, считаются один раз:
+// сравнение с другой фигурой сводится к поиску её хэша в наборе
+func (p *Pattern) cacheVariantHashes() {
+	var rotated, aligned *Pattern
+	if p.variantHashes != nil {
+		return
+	}
+	p.rotationHashes = make(map[string]bool, 6)
+	p.variantHashes = make(map[string]bool, 12)
+	rotated = p
+	for i := 1; i <= 6; i++ {
+		aligned = rotated.getAligned(3)
+		aligned.validateHash()
+		p.rotationHashes[aligned.patternHash] = true
+		p.variantHashes[aligned.patternHash] = true
+		aligned = rotated.getReflected(3).getAligned(3)
+		aligned.validateHash()
+		p.variantHashes[aligned.patternHash] = true
+		if i < 6 {
+			rotated = rotated.getRotated(1)
+		}
+	}
+}
+
+func (p *Pattern) matches(other *Pattern, allowReflection bool) bool {
+	if p.Len() != other.Len() {
+		return false
+	}
+	p.cacheVariantHashes()
+	aligned := other.getAligned(3)
+	aligned.validateHash()
+	if allowReflection {
+		return p.variantHashes[aligned.patternHash]
+	}
+	return p.rotationHashes[aligned.patternHash]
+}
+
+func (p *Pattern) getCanonical() string {
+	var rotated, aligned *Pattern
+	var canonical string
+	rotated = p
+	for i := 1; i <= 6; i++ {
+		for j := 1; j <= 2; j++ {
+			for freeAxis := 1; freeAxis <= 3; freeAxis++ {
+				if j == 1 {
+					aligned = rotated.getAligned(freeAxis)
+				} else {
+					aligned = rotated.getReflected(freeAxis).getAligned(freeAxis)
+				}
+				aligned.validateHash()
+				if canonical == "" || aligned.patternHash < canonical {
+					canonical = aligned.patternHash
+				}
+			}
+		}
+		if i < 6 {
+			rotated = rotated.getRotated(1)
+		}
+	}
+	return canonical
+}
+
+// Key возвращает каноническую форму фигуры, не зависящую от поворотов,
+// отражений и сдвигов: a.isEqual(b) тогда и только тогда, когда a.Key() == b.Key().
+// В отличие от patternHash ключ годится для использования в map;
+// он записан координатами треугольников и сохраняется в JSON.
+func (p *Pattern) Key() string {
+	return formatHash(p.getCanonical())
+}
+
+// группа симметрий фигуры: Cn - только n поворотов, Dn - n поворотов
+// и столько же отражений
+func (p *Pattern) symmetryGroup() string {
+	rotations, reflections := p.symmetryCounts()
+	if reflections > 0 {
+		return fmt.Sprintf("D%d", rotations)
+	}
+	return fmt.Sprintf("C%d", rotations)
+}
+
+// число поворотов (вместе с тождественным) и отражений,
+// переводящих фигуру в её сдвиг
+func (p *Pattern) symmetryCounts() (int, int) {
+	var rotated, aligned *Pattern
+	var rotations, reflections int
+	base := p.getAligned(3)
+	base.validateHash()
+	rotated = p
+	for i := 1; i <= 6; i++ {
+		aligned = rotated.getAligned(3)
+		aligned.validateHash()
+		if aligned.patternHash == base.patternHash {
+			rotations++
+		}
+		aligned = rotated.getReflected(3).getAligned(3)
+		aligned.validateHash()
+		if aligned.patternHash == base.patternHash {
+			reflections++
+		}
+		if i < 6 {
+			rotated = rotated.getRotated(1)
+		}
+	}
+	return rotations, reflections
+}
+
+func (p *Pattern) allVariants() []*Pattern {
+	var rotated, aligned *Pattern
+	variants := make([]*Pattern, 0, 12)
+	seen := make(map[string]bool, 12)
+	rotated = p
+	for i := 1; i <= 6; i++ {
+		for j := 1; j <= 2; j++ {
+			if j == 1 {
+				aligned = rotated.getAligned(3)
+			} else {
+				aligned = rotated.getReflected(3).getAligned(3)
+			}
+			aligned.validateHash()
+			if !seen[aligned.patternHash] {
+				seen[aligned.patternHash] = true
+				variants = append(variants, aligned)
+			}
+		}
+		if i < 6 {
+			rotated = rotated.getRotated(1)
+		}
+	}
+	return variants
+}
+
+func (p *Pattern) IsConnected() bool {
+	return p.countReachable(false) == p.Len()
+}
+
+func (p *Pattern) isVertexConnected() bool {
+	return p.countReachable(true) == p.Len()
+}
+
+func (p *Pattern) isVertexConnectedOnly() bool {
+	return p.isVertexConnected() && !p.IsConnected()
+}
+
+func (p *Pattern) countReachable(throughVertices bool) int {
+	var t Triangle
+	var adjacent []*Triangle
+	if len(p.triangles) == 0 {
+		return 0
+	}
+	visited := make(map[Triangle]bool, len(p.triangles))
+	queue := []Triangle{p.triangles[0]}
+	visited[queue[0]] = true
+	for len(queue) > 0 {
+		t = queue[0]
+		queue = queue[1:]
+		adjacent = []*Triangle{t.getNeighbour(1), t.getNeighbour(2), t.getNeighbour(3)}
+		if throughVertices {
+			adjacent = append(adjacent, t.getVertexNeighbours()...)
+		}
+		for i := 0; i < len(adjacent); i++ {
+			if p.contains(adjacent[i]) && !visited[*adjacent[i]] {
+				visited[*adjacent[i]] = true
+				queue = append(queue, *adjacent[i])
+			}
+		}
+	}
+	return len(visited)
+}
+
+// пустые клетки, примыкающие к фигуре сторонами, без повторов
+func (p *Pattern) frontier() []*Triangle {
+	var tn *Triangle
+	seen := make(map[Triangle]bool)
+	result := make([]*Triangle, 0)
+	for i := 0; i < len(p.triangles); i++ {
+		for axis := 1; axis <= 3; axis++ {
+			tn = p.triangles[i].getNeighbour(axis)
+			if p.contains(tn) || seen[*tn] {
+				continue
+			}
+			seen[*tn] = true
+			result = append(result, tn)
+		}
+	}
+	return result
+}
+
+func (p *Pattern) hasHoles() bool {
+	var t, tn Triangle
+	minX, minY, minZ, maxX, maxY, maxZ := p.bounds()
+	minX, minY, minZ = minX-1, minY-1, minZ-1
+	maxX, maxY, maxZ = maxX+1, maxY+1, maxZ+1
+	inBox := func(t Triangle) bool {
+		return t.x >= minX && t.x <= maxX && t.y >= minY && t.y <= maxY && t.z >= minZ && t.z <= maxZ
+	}
+	empty := make([]Triangle, 0)
+	outside := make(map[Triangle]bool)
+	queue := make([]Triangle, 0)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for _, z := range []int{1 - x - y, -1 - x - y} {
+				t = Triangle{x: x, y: y, z: z}
+				if !inBox(t) || p.contains(&t) {
+					continue
+				}
+				empty = append(empty, t)
+				if x == minX || x == maxX || y == minY || y == maxY || z == minZ || z == maxZ {
+					outside[t] = true
+					queue = append(queue, t)
+				}
+			}
+		}
+	}
+	for len(queue) > 0 {
+		t = queue[0]
+		queue = queue[1:]
+		for axis := 1; axis <= 3; axis++ {
+			tn = *t.getNeighbour(axis)
+			if inBox(tn) && !p.contains(&tn) && !outside[tn] {
+				outside[tn] = true
+				queue = append(queue, tn)
+			}
+		}
+	}
+	return len(outside) < len(empty)
+}
+
+// индекс строится лениво: фигуры, получаемые поворотами и сдвигами
+// при сравнении, проверку принадлежности не используют
+func (p *Pattern) buildIndex() {
+	if p.members != nil {
+		return
+	}
+	p.members = make(map[Triangle]bool, len(p.triangles))
+	for i := 0; i < len(p.triangles); i++ {
+		p.members[p.triangles[i]] = true
+	}
+}
+
+func (p *Pattern) contains(t *Triangle) bool {
+	p.buildIndex()
+	return p.members[*t]
+}
+
+func (p *Pattern) addTriangle(t *Triangle) {
+	p.triangles = append(p.triangles, *t)
+	if p.members != nil {
+		p.members[*t] = true
+	}
+	p.validHash = false
+	p.rotationHashes = nil
+	p.variantHashes = nil
+}
+
+// BoundingBox возвращает наименьшие и наибольшие координаты x, y, z
+// треугольников фигуры
+func (p *Pattern) BoundingBox() (int, int, int, int, int, int) {
+	return p.bounds()
+}
+
+// Width - ширина фигуры в высотах треугольника (число вертикальных полос сетки)
+func (p *Pattern) Width() int {
+	x1, _, x2, _ := p.cartesianBounds()
+	return int(math.Round((x2 - x1) * tg30x2))
+}
+
+// Height - высота фигуры в длинах стороны треугольника
+func (p *Pattern) Height() float64 {
+	_, y1, _, y2 := p.cartesianBounds()
+	return y2 - y1
+}
+
+func (p *Pattern) bounds() (int, int, int, int, int, int) {
+	var minX, minY, minZ, maxX, maxY, maxZ int
+	var t *Triangle
+	if len(p.triangles) == 0 {
+		return 0, 0, 0, 0, 0, 0
+	}
+	t = &p.triangles[0]
+	minX, minY, minZ = t.x, t.y, t.z
+	maxX, maxY, maxZ = t.x, t.y, t.z
+	for i := 1; i < len(p.triangles); i++ {
+		t = &p.triangles[i]
+		minX = min(minX, t.x)
+		minY = min(minY, t.y)
+		minZ = min(minZ, t.z)
+		maxX = max(maxX, t.x)
+		maxY = max(maxY, t.y)
+		maxZ = max(maxZ, t.z)
+	}
+	return minX, minY, minZ, maxX, maxY, maxZ
+}
+
+func (p *Pattern) getShifted(shift, axis int) *Pattern {
+	shifted := NewPattern()
+	for i := 0; i < len(p.triangles); i++ {
+		shifted.addTriangle(p.triangles[i].getShifted(shift, axis))
+	}
+	return shifted
+}
+
+func (p *Pattern) getRotated(angle int) *Pattern {
+	rotated := NewPattern()
+	for i := 0; i < len(p.triangles); i++ {
+		rotated.addTriangle(p.triangles[i].getRotated(angle))
+	}
+	return rotated
+}
+
+func (p *Pattern) getReflected(axis int) *Pattern {
+	reflected := NewPattern()
+	for i := 0; i < len(p.triangles); i++ {
+		reflected.addTriangle(p.triangles[i].getReflected(axis))
+	}
+	return reflected
+}
+
+func (p *Pattern) getAligned(freeAxis int) *Pattern {
+	var aligned *Pattern
+	minX, minY, minZ, maxX, maxY, maxZ := p.bounds()
+	// сдвиг вдоль оси не меняет координату по этой оси,
+	// поэтому минимум берётся из исходных границ
+	switch freeAxis {
+	case 1:
+		aligned = p.getShifted(maxY, 3)
+		aligned = aligned.getShifted(-minZ, 2)
+	case 2:
+		aligned = p.getShifted(maxZ, 1)
+		aligned = aligned.getShifted(-minX, 3)
+	case 3:
+		aligned = p.getShifted(maxX, 2)
+		aligned = aligned.getShifted(-minY, 1)
+	}
+	return aligned
+}
+
+func (p *Pattern) getCentered() *Pattern {
+	var centered *Pattern
+	minX, minY, _, maxX, maxY, _ := p.bounds()
+	centered = p.getShifted((minX+maxX)/2, 2)
+	centered = centered.getShifted(-(minY+maxY)/2, 1)
+	return centered
+}
+
+func (p *Pattern) centroid() (float64, float64) {
+	var x, y, cx, cy float64
+	if len(p.triangles) == 0 {
+		return 0, 0
+	}
+	for i := 0; i < len(p.triangles); i++ {
+		x, y = p.triangles[i].getCenter()
+		cx += x
+		cy += y
+	}
+	return cx / float64(len(p.triangles)), cy / float64(len(p.triangles))
+}
+
+func (p *Pattern) cartesianBounds() (float64, float64, float64, float64) {
+	var v [3][2]float64
+	var xMin, yMin, xMax, yMax float64
+	for i := 0; i < len(p.triangles); i++ {
+		v = p.triangles[i].vertices()
+		if i == 0 {
+			xMin, yMin, xMax, yMax = v[0][0], v[0][1], v[0][0], v[0][1]
+		}
+		for j := 0; j < len(v); j++ {
+			xMin = min(v[j][0], xMin)
+			yMin = min(v[j][1], yMin)
+			xMax = max(v[j][0], xMax)
+			yMax = max(v[j][1], yMax)
+		}
+	}
+	return xMin, yMin, xMax, yMax
+}
+
+// Perimeter возвращает периметр фигуры в сторонах треугольников
+func (p *Pattern) Perimeter() int {
+	return p.boundaryEdgeCount()
+}
+
+// число сторон треугольников, за которыми нет соседа из фигуры
+func (p *Pattern) boundaryEdgeCount() int {
+	count := 0
+	for i := 0; i < len(p.triangles); i++ {
+		for axis := 1; axis <= 3; axis++ {
+			if !p.contains(p.triangles[i].getNeighbour(axis)) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (p *Pattern) quickSignature() (int, int) {
+	return p.Len(), p.boundaryEdgeCount()
+}
+
+func (p *Pattern) boundaryEdges() []line {
+	var x1, y1, x2, y2 float64
+	var t *Triangle
+	edges := make([]line, 0, len(p.triangles)*3)
+	for i := 0; i < len(p.triangles); i++ {
+		t = &p.triangles[i]
+		for axis := 1; axis <= 3; axis++ {
+			if p.contains(t.getNeighbour(axis)) {
+				continue
+			}
+			x1, y1, x2, y2 = t.getCartesianCoords(axis)
+			edges = append(edges, newLine(x1, y1, x2, y2, true))
+		}
+	}
+	return edges
+}
+
+type line struct {
+	x1, y1, x2, y2 float64
+	bold           bool
+}
+
+func newLine(x1, y1, x2, y2 float64, bold bool) line {
+	return line{
+		x1:   x1,
+		y1:   y1,
+		x2:   x2,
+		y2:   y2,
+		bold: bold,
+	}
+}
+
+type RenderOptions struct {
+	Size        int
+	ShowIndex   bool
+	Heatmap     bool
+	OutlineOnly bool
+	Transparent bool
+	Fill        bool
+	UpColor     string
+	DownColor   string
+	// один цвет заливки для всей фигуры вместо цветов по направлению
+	FillColor string
+	// каждый треугольник своим цветом
+	CellColors   bool
+	SharpCorners bool
+	HexClip      bool
+	Legend       bool
+	// не рисовать сетку и оси; GridUnderShape - сетка только
+	// в треугольниках между наименьшими и наибольшими координатами фигуры
+	NoGrid         bool
+	NoAxes         bool
+	GridUnderShape bool
+	// отступ по краям в пикселях (0 - indent)
+	Padding float64
+	// минимальные ширина и высота изображения в пикселях
+	MinSize int
+	// формат файлов: "png" (по умолчанию) или "svg"
+	Format string
+	// добавлять группу симметрии фигуры к имени файла
+	SymmetryInName bool
+	// сохранять рядом с изображением файл .json со свойствами фигуры
+	Sidecar bool
+	// тема оформления: "light" (по умолчанию), "dark" или "print"
+	Theme string
+	// цвета фона, сетки, осей и сторон фигуры вместо цветов темы
+	BackgroundColor string
+	GridColor       string
+	AxesColor       string
+	EdgeColor       string
+	// шаблон имени файла, например "{n}-{index}-{symmetry}.png";
+	// {code} - краткая запись фигуры из Encode;
+	// может содержать подкаталоги, пусто - имя по номеру фигуры
+	NameTemplate string
+	// пикселей на сторону треугольника (0 - scale), если не задан Size
+	Scale float64
+	// наименьший радиус поля зрения в длинах стороны: фигуры меньшего
+	// радиуса рисуются в том же масштабе и на холсте того же размера
+	ViewRadius float64
+	// толщина линий сетки, внутренних и внешних сторон (0 - по умолчанию)
+	GridWidth     float64
+	EdgeWidth     float64
+	BoundaryWidth float64
+}
+
+func (opts RenderOptions) lineWidths() (float64, float64, float64) {
+	grid, edge, boundary := 0.3, 2.0, 5.0
+	if opts.GridWidth > 0 {
+		grid = opts.GridWidth
+	}
+	if opts.EdgeWidth > 0 {
+		edge = opts.EdgeWidth
+	}
+	if opts.BoundaryWidth > 0 {
+		boundary = opts.BoundaryWidth
+	}
+	return grid, edge, boundary
+}
+
+// фигура, по свойствам которой строится имя файла: фигура
+// из треугольников или из клеток другой решётки
+type namedShape interface {
+	Len() int
+	symmetryGroup() string
+	Perimeter() int
+	Encode() string
+}
+
+func (opts RenderOptions) filename(index int, p namedShape) string {
+	return opts.filenameAs(index, p, opts.extension())
+}
+
+// имя файла фигуры с расширением другого формата, например gif или dxf
+func (opts RenderOptions) filenameAs(index int, p namedShape, ext string) string {
+	if opts.NameTemplate != "" {
+		return expandNameTemplate(opts.NameTemplate, index, p, ext)
+	}
+	if opts.SymmetryInName {
+		return fmt.Sprintf("%d_%s.%s", index, p.symmetryGroup(), ext)
+	}
+	return fmt.Sprintf("%d.%s", index, ext)
+}
+
+var namePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// расширения, которые шаблон может указать сам; они заменяются
+// расширением сохраняемого файла
+var templateExtensions = map[string]bool{".png": true, ".svg": true, ".gif": true, ".dxf": true}
+
+// ValidateNameTemplate проверяет, что шаблон имени состоит из известных
+// подстановок и различает фигуры: {index} обязателен
+func ValidateNameTemplate(template string) error {
+	placeholders := namePlaceholder.FindAllString(template, -1)
+	hasIndex := false
+	for i := 0; i < len(placeholders); i++ {
+		switch placeholders[i] {
+		case "{index}":
+			hasIndex = true
+		case "{n}", "{symmetry}", "{perimeter}", "{code}", "{ext}":
+		default:
+			return fmt.Errorf("неизвестная подстановка %s в шаблоне имени, допустимы {n}, {index}, {symmetry}, {perimeter}, {code} и {ext}", placeholders[i])
+		}
+	}
+	if !hasIndex {
+		return fmt.Errorf("шаблон имени %q должен содержать {index}, иначе имена файлов совпадут", template)
+	}
+	if filepath.IsAbs(template) || strings.HasPrefix(filepath.Clean(template), "..") {
+		return fmt.Errorf("шаблон имени %q должен задавать путь внутри каталога результатов", template)
+	}
+	return nil
+}
+
+func expandNameTemplate(template string, index int, p namedShape, ext string) string {
+	name := namePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{n}":
+			return strconv.Itoa(p.Len())
+		case "{index}":
+			return strconv.Itoa(index)
+		case "{symmetry}":
+			return p.symmetryGroup()
+		case "{perimeter}":
+			return strconv.Itoa(p.Perimeter())
+		case "{code}":
+			return p.Encode()
+		case "{ext}":
+			return ext
+		}
+		return placeholder
+	})
+	if templateExtensions[strings.ToLower(filepath.Ext(name))] {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name + "." + ext
+}
+
+// CheckWritable создаёт каталог dir вместе с родительскими
+// и проверяет, что в него можно записывать файлы
+func CheckWritable(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("не удалось создать каталог %s: %v", dir, err)
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("каталог %s недоступен для записи: %v", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func (opts RenderOptions) extension() string {
+	if opts.Format == "svg" {
+		return "svg"
+	}
+	return "png"
+}
+
+// сохраняет фигуру в файл в формате из opts
+func savePattern(p *Pattern, index int, path string, opts RenderOptions) error {
+	var err error
+	// шаблон имени может добавить подкаталоги
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if opts.extension() == "svg" {
+		err = saveAsSVG(p, path, opts)
+	} else {
+		pimg := newPatternImage(opts)
+		pimg.drawPattern(p)
+		err = pimg.saveAsPNG(path)
+	}
+	if err != nil || !opts.Sidecar {
+		return err
+	}
+	return saveSidecar(p, index, path)
+}
+
+type patternImage struct {
+	xMin, yMin, xMax, yMax float64
+	width                  float64
+	height                 float64
+	xCenter, yCenter       float64
+	scale                  float64
+	minRadius              float64
+	shape                  *Pattern
+	opts                   RenderOptions
+	img                    *gg.Context
+}
+
+func newPatternImage(opts RenderOptions) patternImage {
+	pimg := patternImage{
+		scale:     scale,
+		minRadius: opts.ViewRadius,
+		opts:      opts,
+	}
+	if opts.Scale > 0 {
+		pimg.scale = opts.Scale
+	}
+	return pimg
+}
+
+func (pimg *patternImage) toReal(x, y float64) (float64, float64) {
+	return (x-pimg.xCenter)*pimg.scale + pimg.width/2, pimg.height/2 - (y-pimg.yCenter)*pimg.scale
+}
+
+// все стороны треугольников фигуры; внешние помечены как жирные
+func (p *Pattern) edgeLines() []line {
+	var x1, y1, x2, y2 float64
+	var t *Triangle
+	lines := make([]line, 0, len(p.triangles)*3)
+	for i := 0; i < len(p.triangles); i++ {
+		t = &p.triangles[i]
+		for axis := 1; axis <= 3; axis++ {
+			x1, y1, x2, y2 = t.getCartesianCoords(axis)
+			lines = append(lines, newLine(x1, y1, x2, y2, !p.contains(t.getNeighbour(axis))))
+		}
+	}
+	return lines
+}
+
+// вычисляет поле зрения и размер изображения для фигуры,
+// возвращает радиус области вокруг центра фигуры
+func (pimg *patternImage) setView(p *Pattern) float64 {
+	var x1, y1, x2, y2, radius, padding, extra float64
+	pimg.shape = p
+	x1, y1, x2, y2 = p.cartesianBounds()
+	pimg.xCenter = (x1 + x2) / 2
+	pimg.yCenter = (y1 + y2) / 2
+	radius = max(p.cartesianRadius(), pimg.minRadius)
+	margin := 1.0
+	if pimg.opts.Legend {
+		margin = 1.5
+	}
+	pimg.xMin = pimg.xCenter - radius - margin
+	pimg.yMin = pimg.yCenter - radius - margin
+	pimg.xMax = pimg.xCenter + radius + margin
+	pimg.yMax = pimg.yCenter + radius + margin
+	padding = indent
+	if pimg.opts.Padding > 0 {
+		padding = pimg.opts.Padding
+	}
+	if pimg.opts.Size > 0 {
+		pimg.scale = (float64(pimg.opts.Size) - padding) / (pimg.xMax - pimg.xMin)
+	}
+	// маленькие фигуры не сжимаются: поле зрения расширяется до минимального
+	// размера, центр остаётся на месте
+	extra = (float64(pimg.opts.MinSize) - padding - (pimg.xMax-pimg.xMin)*pimg.scale) / pimg.scale / 2
+	if extra > 0 {
+		pimg.xMin -= extra
+		pimg.yMin -= extra
+		pimg.xMax += extra
+		pimg.yMax += extra
+	}
+	pimg.width = max((pimg.xMax-pimg.xMin)*pimg.scale+padding, float64(pimg.opts.MinSize))
+	pimg.height = max((pimg.yMax-pimg.yMin)*pimg.scale+padding, float64(pimg.opts.MinSize))
+	return radius + margin
+}
+
+func (pimg *patternImage) drawPattern(p *Pattern) {
+	viewRadius := pimg.setView(p)
+	pimg.drawBackground(viewRadius)
+
+	if pimg.opts.Heatmap {
+		pimg.drawHeatmap(p)
+	} else if pimg.opts.isFilled() {
+		pimg.drawFill(p)
+	}
+
+	pimg.drawLines(p.edgeLines())
+
+	if pimg.opts.ShowIndex {
+		pimg.drawIndices(p)
+	}
+
+	if pimg.opts.Legend {
+		pimg.drawLegend()
+	}
+}
+
+// создаёт изображение по полю зрения из setView, рисует фон, сетку и оси
+func (pimg *patternImage) drawBackground(viewRadius float64) {
+	pimg.img = gg.NewContext(int(pimg.width), int(pimg.height))
+	if !pimg.opts.SharpCorners {
+		pimg.img.SetLineCapRound()
+		pimg.img.SetLineJoinRound()
+	}
+	if !pimg.opts.Transparent {
+		pimg.img.SetHexColor(pimg.opts.theme().background)
+		pimg.img.Clear()
+	}
+
+	if !pimg.opts.OutlineOnly {
+		if pimg.opts.HexClip {
+			pimg.clipToHexagon(viewRadius)
+		}
+		switch {
+		case pimg.opts.NoGrid:
+		case pimg.opts.GridUnderShape:
+			pimg.drawGridUnderShape()
+		default:
+			pimg.drawGrid()
+		}
+		if !pimg.opts.NoAxes {
+			pimg.drawAxes()
+		}
+		pimg.img.ResetClip()
+	}
+}
+
+func (pimg *patternImage) drawLines(lines []line) {
+	var x1, y1, x2, y2 float64
+	var l line
+	_, edgeWidth, boundaryWidth := pimg.opts.lineWidths()
+	pimg.img.SetHexColor(pimg.opts.theme().edge)
+	for i := 0; i < len(lines); i++ {
+		l = lines[i]
+		x1, y1 = pimg.toReal(l.x1, l.y1)
+		x2, y2 = pimg.toReal(l.x2, l.y2)
+		if l.bold {
+			pimg.img.SetLineWidth(boundaryWidth)
+		} else {
+			pimg.img.SetLineWidth(edgeWidth)
+		}
+		pimg.img.DrawLine(x1, y1, x2, y2)
+		pimg.img.Stroke()
+	}
+}
+
+func (pimg *patternImage) clipToView(x1, y1, x2, y2 float64) (float64, float64, float64, float64, bool) {
+	var p, q, r float64
+	t0, t1 := 0.0, 1.0
+	dx, dy := x2-x1, y2-y1
+	edges := [4][2]float64{
+		{-dx, x1 - pimg.xMin},
+		{dx, pimg.xMax - x1},
+		{-dy, y1 - pimg.yMin},
+		{dy, pimg.yMax - y1},
+	}
+	for i := 0; i < len(edges); i++ {
+		p, q = edges[i][0], edges[i][1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		r = q / p
+		if p < 0 {
+			t0 = max(t0, r)
+		} else {
+			t1 = min(t1, r)
+		}
+	}
+	if t0 > t1 {
+		return 0, 0, 0, 0, false
+	}
+	return x1 + t0*dx, y1 + t0*dy, x1 + t1*dx, y1 + t1*dy, true
+}
+
+func (pimg *patternImage) drawViewLine(x1, y1, x2, y2 float64) {
+	var visible bool
+	x1, y1, x2, y2, visible = pimg.clipToView(x1, y1, x2, y2)
+	if !visible {
+		return
+	}
+	x1, y1 = pimg.toReal(x1, y1)
+	x2, y2 = pimg.toReal(x2, y2)
+	pimg.img.DrawLine(x1, y1, x2, y2)
+	pimg.img.Stroke()
+}
+
+func (pimg *patternImage) clipToHexagon(radius float64) {
+	x, y := pimg.toReal(pimg.xCenter, pimg.yCenter)
+	pimg.img.DrawRegularPolygon(6, x, y, radius*pimg.scale, 0)
+	pimg.img.Clip()
+}
+
+func (pimg *patternImage) drawGrid() {
+	var x, c float64
+	gridWidth, _, _ := pimg.opts.lineWidths()
+	pimg.img.SetHexColor(pimg.opts.theme().grid)
+	pimg.img.SetLineWidth(gridWidth)
+	for x = math.Ceil(pimg.xMin * tg30x2); x <= pimg.xMax*tg30x2; x++ {
+		pimg.drawViewLine(x/tg30x2, pimg.yMin, x/tg30x2, pimg.yMax)
+	}
+	for c = math.Ceil(pimg.yMin - pimg.xMax*tg30); c <= pimg.yMax-pimg.xMin*tg30; c++ {
+		pimg.drawViewLine(pimg.xMin, pimg.xMin*tg30+c, pimg.xMax, pimg.xMax*tg30+c)
+	}
+	for c = math.Ceil(pimg.yMin + pimg.xMin*tg30); c <= pimg.yMax+pimg.xMax*tg30; c++ {
+		pimg.drawViewLine(pimg.xMin, -pimg.xMin*tg30+c, pimg.xMax, -pimg.xMax*tg30+c)
+	}
+}
+
+// стороны всех треугольников сетки, координаты которых не выходят
+// за пределы координат фигуры; получается выпуклая область под фигурой
+func (pimg *patternImage) drawGridUnderShape() {
+	var x1, y1, x2, y2 float64
+	var t *Triangle
+	if pimg.shape == nil || pimg.shape.Len() == 0 {
+		return
+	}
+	minX, minY, minZ, maxX, maxY, maxZ := pimg.shape.bounds()
+	gridWidth, _, _ := pimg.opts.lineWidths()
+	pimg.img.SetHexColor(pimg.opts.theme().grid)
+	pimg.img.SetLineWidth(gridWidth)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for _, z := range []int{1 - x - y, -1 - x - y} {
+				if z < minZ || z > maxZ {
+					continue
+				}
+				t = newTriangle(x, y, z)
+				for axis := 1; axis <= 3; axis++ {
+					x1, y1, x2, y2 = t.getCartesianCoords(axis)
+					x1, y1 = pimg.toReal(x1, y1)
+					x2, y2 = pimg.toReal(x2, y2)
+					pimg.img.DrawLine(x1, y1, x2, y2)
+					pimg.img.Stroke()
+				}
+			}
+		}
+	}
+}
+
+func (pimg *patternImage) drawAxes() {
+	var reach float64
+	reach = (pimg.xMax - pimg.xMin) + (pimg.yMax - pimg.yMin)
+	pimg.img.SetHexColor(pimg.opts.theme().axes)
+	pimg.img.SetLineWidth(1)
+	pimg.drawViewLine(0, 0, 0, reach)
+	pimg.drawViewLine(0, 0, -reach, -reach*tg30)
+	pimg.drawViewLine(0, 0, reach, -reach*tg30)
+}
+
+func (pimg *patternImage) fillTriangle(t *Triangle) {
+	var x, y float64
+	v := t.vertices()
+	for i := 0; i < len(v); i++ {
+		x, y = pimg.toReal(v[i][0], v[i][1])
+		if i == 0 {
+			pimg.img.MoveTo(x, y)
+		} else {
+			pimg.img.LineTo(x, y)
+		}
+	}
+	pimg.img.ClosePath()
+	pimg.img.Fill()
+}
+
+func (opts RenderOptions) isFilled() bool {
+	return opts.Fill || opts.FillColor != "" || opts.CellColors
+}
+
+// цвет заливки i-го треугольника фигуры
+func (opts RenderOptions) fillColor(p *Pattern, i int) string {
+	switch {
+	case opts.CellColors:
+		return paletteColor(i)
+	case opts.FillColor != "":
+		return opts.FillColor
+	case p.triangles[i].isUpward():
+		return opts.UpColor
+	}
+	return opts.DownColor
+}
+
+// i-й цвет палитры; соседние номера получают далёкие оттенки
+func paletteColor(i int) string {
+	r, g, b := hsvToRGB(math.Mod(float64(i)*137.5, 360), 0.45, 1.0)
+	return fmt.Sprintf("#%02x%02x%02x", int(r*255), int(g*255), int(b*255))
+}
+
+func (pimg *patternImage) drawFill(p *Pattern) {
+	for i := 0; i < len(p.triangles); i++ {
+		pimg.img.SetHexColor(pimg.opts.fillColor(p, i))
+		pimg.fillTriangle(&p.triangles[i])
+	}
+}
+
+func (pimg *patternImage) drawHeatmap(p *Pattern) {
+	var cx, cy, x, y, maxDist float64
+	dists := make([]float64, len(p.triangles))
+	cx, cy = p.centroid()
+	maxDist = 0.0
+	for i := 0; i < len(p.triangles); i++ {
+		x, y = p.triangles[i].getCenter()
+		dists[i] = math.Hypot(x-cx, y-cy)
+		maxDist = max(maxDist, dists[i])
+	}
+	for i := 0; i < len(p.triangles); i++ {
+		if maxDist > 0 {
+			pimg.img.SetRGB(hsvToRGB(240*(1-dists[i]/maxDist), 0.6, 1.0))
+		} else {
+			pimg.img.SetRGB(hsvToRGB(240, 0.6, 1.0))
+		}
+		pimg.fillTriangle(&p.triangles[i])
+	}
+}
+
+func hsvToRGB(h, s, v float64) (float64, float64, float64) {
+	var r, g, b float64
+	c := v * s
+	hh := math.Mod(h/60, 6)
+	x := c * (1 - math.Abs(math.Mod(hh, 2)-1))
+	switch {
+	case hh < 1:
+		r, g, b = c, x, 0
+	case hh < 2:
+		r, g, b = x, c, 0
+	case hh < 3:
+		r, g, b = 0, c, x
+	case hh < 4:
+		r, g, b = 0, x, c
+	case hh < 5:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	m := v - c
+	return r + m, g + m, b + m
+}
+
+func (pimg *patternImage) setFontSize(size float64) bool {
+	font, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		return false
+	}
+	pimg.img.SetFontFace(truetype.NewFace(font, &truetype.Options{Size: size}))
+	return true
+}
+
+func (pimg *patternImage) drawLegend() {
+	var reach, x1, y1, x2, y2, x, y, shift, anchor float64
+	var visible bool
+	if !pimg.setFontSize(pimg.scale / 5) {
+		return
+	}
+	reach = (pimg.xMax - pimg.xMin) + (pimg.yMax - pimg.yMin)
+	tips := [3][2]float64{
+		{0, reach},
+		{-reach, -reach * tg30},
+		{reach, -reach * tg30},
+	}
+	pimg.img.SetHexColor(pimg.opts.theme().axes)
+	for i := 0; i < len(tips) && !pimg.opts.NoAxes; i++ {
+		x1, y1, x2, y2, visible = pimg.clipToView(0, 0, tips[i][0], tips[i][1])
+		if !visible {
+			continue
+		}
+		// подпись немного отступает от края вдоль оси
+		x = x2 - (x2-x1)*0.25/math.Hypot(x2-x1, y2-y1)
+		y = y2 - (y2-y1)*0.25/math.Hypot(x2-x1, y2-y1)
+		x, y = pimg.toReal(x, y)
+		shift, anchor = pimg.scale/10, 0
+		if tips[i][0] > 0 {
+			shift, anchor = -shift, 1
+		}
+		pimg.img.DrawStringAnchored(fmt.Sprintf("%d", i+1), x+shift, y, anchor, 0.5)
+	}
+
+	x1, y1 = pimg.toReal(pimg.xMin+0.25, pimg.yMin+0.25)
+	x2 = x1 + pimg.scale
+	pimg.img.SetLineWidth(3)
+	pimg.img.DrawLine(x1, y1, x2, y1)
+	pimg.img.Stroke()
+	pimg.img.DrawLine(x1, y1-5, x1, y1+5)
+	pimg.img.Stroke()
+	pimg.img.DrawLine(x2, y1-5, x2, y1+5)
+	pimg.img.Stroke()
+	pimg.img.DrawStringAnchored(fmt.Sprintf("%.0f px", pimg.scale), (x1+x2)/2, y1-5, 0.5, 0)
+}
+
+func (pimg *patternImage) drawIndices(p *Pattern) {
+	var x, y float64
+	if !pimg.setFontSize(pimg.scale / 4) {
+		return
+	}
+	pimg.img.SetHexColor(pimg.opts.theme().edge)
+	for i := 0; i < len(p.triangles); i++ {
+		x, y = pimg.toReal(p.triangles[i].getCenter())
+		pimg.img.DrawStringAnchored(fmt.Sprintf("%d", i), x, y, 0.5, 0.5)
+	}
+}
+
+func (pimg *patternImage) saveAsPNG(path string) error {
+	return pimg.img.SavePNG(path)
+}
+
+func (p *Pattern) cartesianRadius() float64 {
+	x1, y1, x2, y2 := p.cartesianBounds()
+	return max(x2-x1, y2-y1) / 2
+}
+
+// CommonViewRadius - наибольший радиус фигур всех коллекций: с ним
+// в RenderOptions.ViewRadius фигуры рисуются на холстах одного размера
+func CommonViewRadius(collections []*Collection) float64 {
+	radius := 0.0
+	for i := 0; i < len(collections); i++ {
+		for j := 0; j < len(collections[i].patterns); j++ {
+			radius = max(radius, collections[i].patterns[j].cartesianRadius())
+		}
+	}
+	return radius
+}
+
+// все фигуры на одном листе: сетка из columns столбцов, под каждой фигурой
+// её номер; фигуры рисуются в одном масштабе
+func renderMontage(ps []*Pattern, opts RenderOptions, columns int) *gg.Context {
+	var pimg patternImage
+	var radius, labelHeight, x, y float64
+	var width, height, rows int
+	if opts.Size <= 0 {
+		opts.Size = montageCellSize
+	}
+	columns = max(min(columns, len(ps)), 1)
+	rows = (len(ps) + columns - 1) / columns
+	radius = 0.0
+	for i := 0; i < len(ps); i++ {
+		radius = max(radius, ps[i].cartesianRadius())
+	}
+	labelHeight = 20.0
+	dc := gg.NewContext(1, 1)
+	for i := 0; i < len(ps); i++ {
+		pimg = newPatternImage(opts)
+		pimg.minRadius = max(radius, opts.ViewRadius)
+		pimg.drawPattern(ps[i])
+		if i == 0 {
+			width = int(pimg.width)
+			height = int(pimg.height) + int(labelHeight)
+			dc = gg.NewContext(width*columns, height*rows)
+			dc.SetHexColor(opts.theme().background)
+			dc.Clear()
+		}
+		x = float64(i % columns * width)
+		y = float64(i / columns * height)
+		dc.DrawImage(pimg.img.Image(), int(x), int(y))
+		dc.SetHexColor(opts.theme().edge)
+		dc.DrawStringAnchored(fmt.Sprintf("%d", i), x+float64(width)/2, y+float64(height)-labelHeight/2, 0.5, 0.5)
+		dc.SetLineWidth(1)
+		dc.DrawRectangle(x, y, float64(width), float64(height))
+		dc.Stroke()
+	}
+	return dc
+}
+
+func (pc *Collection) SaveMontage(path string, opts RenderOptions, columns int) error {
+	if len(pc.patterns) == 0 {
+		return fmt.Errorf("нет фигур для %s", path)
+	}
+	return renderMontage(pc.patterns, opts, columns).SavePNG(path)
+}
+
+func renderComparison(ps []*Pattern, opts RenderOptions) *gg.Context {
+	var pimg patternImage
+	var radius, labelHeight, x float64
+	var width, height int
+	radius = 0.0
+	for i := 0; i < len(ps); i++ {
+		radius = max(radius, ps[i].cartesianRadius())
+	}
+	labelHeight = 30.0
+	dc := gg.NewContext(1, 1)
+	for i := 0; i < len(ps); i++ {
+		pimg = newPatternImage(opts)
+		pimg.minRadius = max(radius, opts.ViewRadius)
+		pimg.drawPattern(ps[i])
+		if i == 0 {
+			width = int(pimg.width)
+			height = int(pimg.height)
+			dc = gg.NewContext(width*len(ps), height+int(labelHeight))
+			dc.SetHexColor(opts.theme().background)
+			dc.Clear()
+		}
+		x = float64(i * width)
+		dc.DrawImage(pimg.img.Image(), int(x), 0)
+		dc.SetHexColor(opts.theme().edge)
+		dc.DrawStringAnchored(fmt.Sprintf("%d", i), x+float64(width)/2, float64(height)+labelHeight/2, 0.5, 0.5)
+		if i > 0 {
+			dc.SetLineWidth(2)
+			dc.DrawLine(x, 0, x, float64(height)+labelHeight)
+			dc.Stroke()
+		}
+	}
+	return dc
+}
+
+func WritePattern(w io.Writer, p *Pattern, opts RenderOptions) error {
+	pimg := newPatternImage(opts)
+	pimg.drawPattern(p)
+	return pimg.img.EncodePNG(w)
+}
+
+// отрисовка не зависит от порядка обхода map, поэтому одинаковые фигуры
+// с одинаковыми настройками дают побайтно одинаковые PNG
+func renderToBytes(p *Pattern, opts RenderOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WritePattern(&buf, p, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type stats struct {
+	nodes      int
+	accepted   int
+	byVariants map[int]int
+	withHoles  int
+	// вычисленные при отборе канонические формы
+	canonicals int
+}
+
+type Collection struct {
+	patterns   []*Pattern
+	mu         sync.Mutex
+	stats      stats
+	onProgress func(nodes, accepted int)
+	// сообщения о контрольных точках; nil - без них
+	log    io.Writer
+	ctx    context.Context
+	stream chan<- *Pattern
+	hashes map[string]bool
+	// фигуры по quickSignature; непустое значение - единственная фигура
+	// с этой сигнатурой, её каноническая форма ещё не вычислена
+	buckets    map[[2]int]*Pattern
+	placements bool
+	// записывать шаги построения каждой фигуры
+	recordSteps bool
+	// канонические формы уже известных фигур, они не попадают в результат
+	known map[string]bool
+	// при проверке по лемме Бернсайда получает каждую фиксированную
+	// фигуру перебора Редельмейера вместо отбора канонических
+	onFixed func(p *Pattern)
+	// ограничение на клетки фигуры; nil - без ограничений
+	allowed func(*Triangle) bool
+	// при параллельной генерации: заготовки длины splitAt отдаются
+	// в tasks, найденные фигуры собираются в shared
+	tasks   chan<- splitTask
+	splitAt int
+	// при распределённом переборе получает заготовки вместо tasks;
+	// состояние заготовки не копируется
+	onTask   func(task splitTask)
+	numTasks int
+	shared   *sharedSet
+	task     int
+	seq      int
+	// каталог контрольных точек параллельной генерации; пусто - без них
+	checkpointDir  string
+	checkpointSize int
+	checkpointSeed string
+}
+
+type splitTask struct {
+	index  int
+	toAdd  int
+	sketch *Pattern
+	// заготовка перебора Редельмейера вместо sketch
+	search  *redelmeierSearch
+	untried []untriedCell
+}
+
+type sharedEntry struct {
+	task, seq int
+	p         *Pattern
+}
+
+// общий для потоков набор фигур; из повторов остаётся найденная раньше
+// всех при обходе в один поток, поэтому порядок фигур от числа потоков не зависит
+type sharedSet struct {
+	mu      sync.Mutex
+	entries map[string]*sharedEntry
+}
+
+// упорядочивает фигуры по канонической форме, чтобы номера фигур
+// не зависели от порядка перебора
+func (pc *Collection) sortCanonical() {
+	keys := make(map[*Pattern]string, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		keys[pc.patterns[i]] = pc.patterns[i].Key()
+		pc.patterns[i].validateHash()
+	}
+	sort.SliceStable(pc.patterns, func(i, j int) bool {
+		a, b := pc.patterns[i], pc.patterns[j]
+		if keys[a] != keys[b] {
+			return keys[a] < keys[b]
+		}
+		return a.patternHash < b.patternHash
+	})
+}
+
+func (pc *Collection) Patterns() []*Pattern {
+	return pc.patterns
+}
+
+func NewCollection() *Collection {
+	return &Collection{
+		patterns: make([]*Pattern, 0),
+	}
+}
+
+// сообщение о ходе работы, которое не прерывает её; nil w - без вывода
+func logf(w io.Writer, format string, args ...any) {
+	if w != nil {
+		fmt.Fprintf(w, format, args...)
+	}
+}
+
+// уникальность проверяется по канонической форме фигуры
+func (pc *Collection) isNew(hash string) bool {
+	if pc.hashes == nil {
+		pc.hashes = make(map[string]bool)
+	}
+	if pc.hashes[hash] {
+		return false
+	}
+	pc.hashes[hash] = true
+	return pc.known == nil || !pc.known[hash]
+}
+
+func (pc *Collection) appendUnique(p *Pattern) bool {
+	if pc.stream != nil {
+		return pc.sendUnique(p)
+	}
+	if pc.shared != nil {
+		return pc.appendShared(p)
+	}
+	if pc.placements {
+		return pc.appendPlacement(p)
+	}
+	return pc.appendBucketed(p)
+}
+
+// фигура с ещё не встречавшейся сигнатурой заведомо новая, поэтому
+// каноническая форма считается, только когда сигнатура повторяется
+func (pc *Collection) appendBucketed(p *Pattern) bool {
+	if pc.known != nil {
+		pc.stats.canonicals++
+		return pc.appendCanonical(p, p.getCanonical())
+	}
+	if pc.buckets == nil {
+		pc.buckets = make(map[[2]int]*Pattern)
+		for i := 0; i < len(pc.patterns); i++ {
+			pc.buckets[pc.patterns[i].signatureKey()] = nil
+		}
+	}
+	key := p.signatureKey()
+	first, seen := pc.buckets[key]
+	if !seen {
+		if !pc.appendDeferred(p) {
+			return false
+		}
+		pc.buckets[key] = pc.patterns[len(pc.patterns)-1]
+		return true
+	}
+	if first != nil {
+		pc.stats.canonicals++
+		pc.isNew(first.getCanonical())
+		pc.buckets[key] = nil
+	}
+	pc.stats.canonicals++
+	return pc.appendCanonical(p, p.getCanonical())
+}
+
+func (p *Pattern) signatureKey() [2]int {
+	numTriangles, numEdges := p.quickSignature()
+	return [2]int{numTriangles, numEdges}
+}
+
+// canonical - уже вычисленная каноническая форма p
+func (pc *Collection) appendCanonical(p *Pattern, canonical string) bool {
+	if !pc.isNew(canonical) {
+		return false
+	}
+	return pc.appendDeferred(p)
+}
+
+// добавляет p, не записывая её каноническую форму
+func (pc *Collection) appendDeferred(p *Pattern) bool {
+	centered := p.getCentered()
+	centered.copyBuild(p)
+	centered.normalizeOrder()
+	centered.buildIndex()
+	pc.patterns = append(pc.patterns, centered)
+	pc.stats.accepted++
+	return true
+}
+
+func (pc *Collection) appendShared(p *Pattern) bool {
+	var hash string
+	var stored *Pattern
+	if pc.placements {
+		p.validateHash()
+		hash = p.patternHash
+	} else {
+		hash = p.getCanonical()
+	}
+	if !pc.isNew(hash) {
+		return false
+	}
+	if pc.known != nil && pc.placements && pc.known[p.getCanonical()] {
+		return false
+	}
+	if pc.placements {
+		stored = p
+	} else {
+		stored = p.getCentered()
+		stored.copyBuild(p)
+	}
+	stored.normalizeOrder()
+	stored.buildIndex()
+	pc.seq++
+	pc.shared.mu.Lock()
+	defer pc.shared.mu.Unlock()
+	e := pc.shared.entries[hash]
+	if e != nil && (e.task < pc.task || e.task == pc.task && e.seq < pc.seq) {
+		return false
+	}
+	pc.shared.entries[hash] = &sharedEntry{task: pc.task, seq: pc.seq, p: stored}
+	return true
+}
+
+func (pc *Collection) appendPlacement(p *Pattern) bool {
+	if pc.hashes == nil {
+		pc.hashes = make(map[string]bool)
+	}
+	p.validateHash()
+	if pc.hashes[p.patternHash] {
+		return false
+	}
+	pc.hashes[p.patternHash] = true
+	if pc.known != nil && pc.known[p.getCanonical()] {
+		return false
+	}
+	p.normalizeOrder()
+	p.buildIndex()
+	pc.patterns = append(pc.patterns, p)
+	pc.stats.accepted++
+	return true
+}
+
+func (pc *Collection) sendUnique(p *Pattern) bool {
+	if !pc.isNew(p.getCanonical()) {
+		return false
+	}
+	pc.stats.accepted++
+	centered := p.getCentered()
+	centered.copyBuild(p)
+	centered.normalizeOrder()
+	select {
+	case pc.stream <- centered:
+	case <-pc.ctx.Done():
+	}
+	return true
+}
+
+func (pc *Collection) addUnique(p *Pattern) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.appendUnique(p)
+}
+
+func (pc *Collection) visitNode() {
+	pc.stats.nodes++
+	if pc.onProgress != nil && pc.stats.nodes%progressInterval == 0 {
+		pc.onProgress(pc.stats.nodes, pc.stats.accepted)
+	}
+}
+
+func (pc *Collection) Summarize() string {
+	var sb strings.Builder
+	pc.stats.byVariants = make(map[int]int)
+	pc.stats.withHoles = 0
+	for i := 0; i < len(pc.patterns); i++ {
+		pc.stats.byVariants[len(pc.patterns[i].allVariants())]++
+		if pc.patterns[i].hasHoles() {
+			pc.stats.withHoles++
+		}
+	}
+	fmt.Fprintf(&sb, "просмотрено вариантов: %d\n", pc.stats.nodes)
+	fmt.Fprintf(&sb, "найдено фигур: %d\n", len(pc.patterns))
+	for _, numVariants := range []int{1, 2, 3, 4, 6, 12} {
+		fmt.Fprintf(&sb, "  с %d различными положениями: %d\n", numVariants, pc.stats.byVariants[numVariants])
+	}
+	groups := make(map[string]int)
+	for i := 0; i < len(pc.patterns); i++ {
+		groups[pc.patterns[i].symmetryGroup()]++
+	}
+	sb.WriteString("по группам симметрии:")
+	for _, group := range []string{"C1", "C2", "C3", "C6", "D1", "D2", "D3", "D6"} {
+		if groups[group] > 0 {
+			fmt.Fprintf(&sb, " %s: %d", group, groups[group])
+		}
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "с дырами: %d\n", pc.stats.withHoles)
+	return sb.String()
+}
+
+func (pc *Collection) generatePatternsWithProgress(toAdd int, sketch *Pattern, onProgress func(nodes, accepted int)) {
+	pc.onProgress = onProgress
+	pc.generatePatterns(toAdd, sketch)
+	pc.onProgress = nil
+}
+
+func (pc *Collection) generatePatternsCtx(ctx context.Context, toAdd int, sketch *Pattern) error {
+	pc.ctx = ctx
+	pc.generatePatterns(toAdd, sketch)
+	pc.ctx = nil
+	return ctx.Err()
+}
+
+func (pc *Collection) generatePatternsStream(ctx context.Context, toAdd int, sketch *Pattern) <-chan *Pattern {
+	ch := make(chan *Pattern)
+	go func() {
+		defer close(ch)
+		pc.stream = ch
+		pc.generatePatternsCtx(ctx, toAdd, sketch)
+		pc.stream = nil
+	}()
+	return ch
+}
+
+// Generate перебирает фигуры из numTriangles треугольников и передаёт
+// каждую новую в fn сразу, как она найдена, не накапливая их в коллекции;
+// перебор прекращается, если fn вернёт false или ctx будет отменён
+func (pc *Collection) Generate(ctx context.Context, numTriangles int, fn func(p *Pattern) bool) error {
+	genCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := pc.generatePatternsStream(genCtx, numTriangles, NewPattern())
+	for p := range ch {
+		if !fn(p) {
+			cancel()
+			for range ch {
+			}
+			break
+		}
+	}
+	return ctx.Err()
+}
+
+// генерация в workers потоков: первые уровни дерева перебора обходятся
+// в одном потоке, а поддеревья с корнями в заготовках длины splitAt
+// раздаются потокам
+func (pc *Collection) generatePatternsParallel(ctx context.Context, toAdd int, sketch *Pattern, workers int) error {
+	var wg sync.WaitGroup
+	tasks := make(chan splitTask)
+	shared := &sharedSet{entries: make(map[string]*sharedEntry)}
+	locals := make([]*Collection, workers+1)
+	progressed := 0
+	for i := 0; i < len(locals); i++ {
+		locals[i] = &Collection{
+			ctx:         ctx,
+			placements:  pc.placements,
+			recordSteps: pc.recordSteps,
+			known:       pc.known,
+			allowed:     pc.allowed,
+			shared:      shared,
+		}
+		if pc.onProgress != nil {
+			locals[i].onProgress = func(nodes, accepted int) {
+				shared.mu.Lock()
+				progressed += progressInterval
+				pc.onProgress(pc.stats.nodes+progressed, pc.stats.accepted+len(shared.entries))
+				shared.mu.Unlock()
+			}
+		}
+	}
+	done := make(map[int]bool)
+	if pc.checkpointDir != "" {
+		if err := pc.loadCheckpoint(shared, done); err != nil {
+			logf(pc.log, "%v, перебор начинается заново\n", err)
+			shared.entries = make(map[string]*sharedEntry)
+			done = make(map[int]bool)
+		}
+	}
+	for w := 1; w <= workers; w++ {
+		wg.Add(1)
+		go func(local *Collection) {
+			defer wg.Done()
+			for task := range tasks {
+				shared.mu.Lock()
+				skip := done[task.index]
+				shared.mu.Unlock()
+				if skip {
+					continue
+				}
+				local.task = task.index
+				local.hashes = nil
+				if task.search != nil {
+					local.extendRedelmeier(task.search, task.untried, task.toAdd)
+				} else {
+					local.generatePatterns(task.toAdd, task.sketch)
+				}
+				if ctx.Err() == nil {
+					shared.mu.Lock()
+					done[task.index] = true
+					shared.mu.Unlock()
+				}
+			}
+		}(locals[w])
+	}
+	stopSaving := make(chan struct{})
+	var saver sync.WaitGroup
+	if pc.checkpointDir != "" {
+		saver.Add(1)
+		go func() {
+			defer saver.Done()
+			ticker := time.NewTicker(checkpointInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := pc.saveCheckpoint(shared, done); err != nil {
+						logf(pc.log, "%v\n", err)
+					}
+				case <-stopSaving:
+					return
+				}
+			}
+		}()
+	}
+	producer := locals[0]
+	producer.tasks = tasks
+	producer.splitAt = min(sketch.Len()+4, sketch.Len()+toAdd-1)
+	producer.task = -1
+	producer.generatePatterns(toAdd, sketch)
+	close(tasks)
+	wg.Wait()
+	if pc.checkpointDir != "" {
+		close(stopSaving)
+		saver.Wait()
+		if ctx.Err() != nil {
+			if err := pc.saveCheckpoint(shared, done); err != nil {
+				logf(pc.log, "%v\n", err)
+			}
+		} else {
+			if err := pc.removeCheckpoint(); err != nil {
+				logf(pc.log, "%v\n", err)
+			}
+		}
+	}
+
+	entries := make([]*sharedEntry, 0, len(shared.entries))
+	for _, e := range shared.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].task != entries[j].task {
+			return entries[i].task < entries[j].task
+		}
+		return entries[i].seq < entries[j].seq
+	})
+	for i := 0; i < len(entries); i++ {
+		pc.patterns = append(pc.patterns, entries[i].p)
+	}
+	pc.stats.accepted += len(entries)
+	for i := 0; i < len(locals); i++ {
+		pc.stats.nodes += locals[i].stats.nodes
+	}
+	return ctx.Err()
+}
+
+func (pc *Collection) generatePatterns(toAdd int, sketch *Pattern) {
+	var neighbour *Triangle
+	var newSketch *Pattern
+	if pc.ctx != nil && pc.ctx.Err() != nil {
+		return
+	}
+	if pc.tasks != nil && toAdd > 0 && sketch.Len() >= pc.splitAt {
+		select {
+		case pc.tasks <- splitTask{index: pc.numTasks, toAdd: toAdd, sketch: sketch}:
+			pc.numTasks++
+		case <-pc.ctx.Done():
+		}
+		return
+	}
+	pc.visitNode()
+	if toAdd <= 0 {
+		pc.appendUnique(sketch)
+		return
+	}
+	if sketch.Len() == 0 && pc.allowed != nil {
+		// фигура в ограниченной области не обязана содержать начальный
+		// треугольник, поэтому построение начинается из каждой клетки области
+		cells := regionCells(pc.allowed)
+		for i := 0; i < len(cells) && (pc.ctx == nil || pc.ctx.Err() == nil); i++ {
+			newSketch = NewPattern()
+			newSketch.addTriangle(cells[i])
+			newSketch.buildRoot = *cells[i]
+			if toAdd > 1 {
+				pc.generatePatterns(toAdd-1, newSketch)
+			} else {
+				pc.appendUnique(newSketch)
+			}
+		}
+		return
+	}
+	if sketch.Len() == 0 && !pc.placements {
+		pc.generateRedelmeier(toAdd)
+		return
+	}
+	if sketch.Len() == 0 {
+		sketch.addTriangle(newTriangle(0, 1, 0))
+		sketch.buildRoot = sketch.triangles[0]
+		if toAdd > 1 {
+			pc.generatePatterns(toAdd-1, sketch)
+		} else {
+			pc.appendUnique(sketch)
+		}
+		return
+	}
+	// одиночный треугольник и ромб из двух симметричны относительно всех
+	// своих соседей, поэтому достаточно первого из них
+	onlyFirst := sketch.Len() <= 2 && !pc.placements && pc.allowed == nil
+	for i := 0; i < sketch.Len(); i++ {
+		for axis := 1; axis <= 3; axis++ {
+			neighbour = sketch.triangles[i].getNeighbour(axis)
+			if sketch.contains(neighbour) || (pc.allowed != nil && !pc.allowed(neighbour)) {
+				continue
+			}
+			newSketch = sketch.getCopy()
+			newSketch.addTriangle(neighbour)
+			if pc.recordSteps {
+				newSketch.buildSteps = append(sketch.buildSteps[:len(sketch.buildSteps):len(sketch.buildSteps)], buildStep{index: i, axis: axis})
+				newSketch.buildRoot = sketch.buildRoot
+			}
+			if toAdd > 1 {
+				pc.generatePatterns(toAdd-1, newSketch)
+			} else {
+				pc.visitNode()
+				pc.appendUnique(newSketch)
+			}
+			if onlyFirst {
+				return
+			}
+		}
+	}
+}
+
+// клетки области, связной по сторонам и содержащей треугольник 0,1,0;
+// обход ограничен maxRegionCells клетками
+func regionCells(allowed func(*Triangle) bool) []*Triangle {
+	var t, tn *Triangle
+	start := newTriangle(0, 1, 0)
+	if !allowed(start) {
+		return nil
+	}
+	visited := map[Triangle]bool{*start: true}
+	cells := []*Triangle{start}
+	for i := 0; i < len(cells) && len(cells) < maxRegionCells; i++ {
+		t = cells[i]
+		for axis := 1; axis <= 3; axis++ {
+			tn = t.getNeighbour(axis)
+			if !visited[*tn] && allowed(tn) {
+				visited[*tn] = true
+				cells = append(cells, tn)
+			}
+		}
+	}
+	return cells
+}
+
+// треугольная область со стороной side, содержащая треугольник 0,1,0
+func withinTriangle(side int) func(*Triangle) bool {
+	return func(t *Triangle) bool {
+		a, b, c := t.toCube()
+		return a >= 2-side && b >= 0 && c >= 1
+	}
+}
+
+// параллелограмм из width×height ромбов по две клетки, содержащий
+// треугольник 0,1,0
+func withinParallelogram(width, height int) func(*Triangle) bool {
+	return func(t *Triangle) bool {
+		a, b, _ := t.toCube()
+		return a >= 1 && a <= width && b >= 0 && b < height
+	}
+}
+
+// шестиугольная область со стороной side вокруг начала координат
+func withinHexagon(side int) func(*Triangle) bool {
+	limit := 2*side - 1
+	return func(t *Triangle) bool {
+		sum := t.x + t.y + t.z
+		return abs(2*t.x-sum) <= limit && abs(2*t.y-sum) <= limit && abs(2*t.z-sum) <= limit
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// ParsePattern разбирает фигуру, заданную координатами треугольников
+// "x,y,z x,y,z ..." или краткой записью из Encode
+func ParsePattern(s string) (*Pattern, error) {
+	var t *Triangle
+	var coords [3]int
+	var err error
+	p := NewPattern()
+	fields := strings.Fields(s)
+	if len(fields) == 1 && isEncoded(fields[0]) {
+		return decodePattern(fields[0])
+	}
+	for i := 0; i < len(fields); i++ {
+		parts := strings.Split(fields[i], ",")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("неверные координаты %q", fields[i])
+		}
+		for j := 0; j < 3; j++ {
+			coords[j], err = strconv.Atoi(parts[j])
+			if err != nil {
+				return nil, fmt.Errorf("неверные координаты %q", fields[i])
+			}
+		}
+		t, err = NewTriangle(coords[0], coords[1], coords[2])
+		if err != nil {
+			return nil, err
+		}
+		if p.contains(t) {
+			return nil, fmt.Errorf("треугольник %q повторяется", fields[i])
+		}
+		p.addTriangle(t)
+	}
+	return p, nil
+}
+
+func validatePattern(p *Pattern) error {
+	var t *Triangle
+	if p.Len() == 0 {
+		return fmt.Errorf("фигура не содержит треугольников")
+	}
+	for i := 0; i < len(p.triangles); i++ {
+		t = &p.triangles[i]
+		if _, err := NewTriangle(t.x, t.y, t.z); err != nil {
+			return err
+		}
+		for j := 0; j < i; j++ {
+			if p.triangles[j].isEqual(t) {
+				return fmt.Errorf("треугольник %d,%d,%d повторяется", t.x, t.y, t.z)
+			}
+		}
+	}
+	if !p.IsConnected() {
+		return fmt.Errorf("треугольники должны быть связаны сторонами")
+	}
+	return nil
+}
+
+type jsonTriangle struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+}
+
+type jsonPattern struct {
+	Index       int     `json:"index"`
+	Count       int     `json:"count"`
+	Key         string  `json:"key,omitempty"`
+	Code        string  `json:"code,omitempty"`
+	Perimeter   int     `json:"perimeter,omitempty"`
+	Width       int     `json:"width,omitempty"`
+	Height      float64 `json:"height,omitempty"`
+	Variants    int     `json:"variants,omitempty"`
+	Symmetry    string  `json:"symmetry,omitempty"`
+	Chiral      bool    `json:"chiral,omitempty"`
+	Holes       bool    `json:"holes,omitempty"`
+	Convex      bool    `json:"convex,omitempty"`
+	Compactness float64 `json:"compactness,omitempty"`
+	// наименьшие и наибольшие координаты треугольников
+	BoundingBox *jsonBoundingBox `json:"bounding_box,omitempty"`
+	Triangles   []jsonTriangle   `json:"triangles"`
+}
+
+type jsonBoundingBox struct {
+	MinX int `json:"min_x"`
+	MinY int `json:"min_y"`
+	MinZ int `json:"min_z"`
+	MaxX int `json:"max_x"`
+	MaxY int `json:"max_y"`
+	MaxZ int `json:"max_z"`
+}
+
+func (jp jsonPattern) toPattern() (*Pattern, error) {
+	p := NewPattern()
+	for i := 0; i < len(jp.Triangles); i++ {
+		t, err := NewTriangle(jp.Triangles[i].X, jp.Triangles[i].Y, jp.Triangles[i].Z)
+		if err != nil {
+			return nil, err
+		}
+		p.addTriangle(t)
+	}
+	return p, validatePattern(p)
+}
+
+func newJSONPattern(index int, p *Pattern) jsonPattern {
+	sorted := p.getSortedTriangles()
+	minX, minY, minZ, maxX, maxY, maxZ := p.bounds()
+	jp := jsonPattern{
+		Index:       index,
+		Count:       len(sorted),
+		Key:         p.Key(),
+		Code:        p.Encode(),
+		Perimeter:   p.Perimeter(),
+		Width:       p.Width(),
+		Height:      p.Height(),
+		Variants:    len(p.allVariants()),
+		Symmetry:    p.symmetryGroup(),
+		Chiral:      p.isChiral(),
+		Holes:       p.hasHoles(),
+		Convex:      p.IsConvex(),
+		Compactness: math.Round(p.Compactness()*1e4) / 1e4,
+		BoundingBox: &jsonBoundingBox{MinX: minX, MinY: minY, MinZ: minZ, MaxX: maxX, MaxY: maxY, MaxZ: maxZ},
+		Triangles:   make([]jsonTriangle, 0, len(sorted)),
+	}
+	for j := 0; j < len(sorted); j++ {
+		jp.Triangles = append(jp.Triangles, jsonTriangle{X: sorted[j].x, Y: sorted[j].y, Z: sorted[j].z})
+	}
+	return jp
+}
+
+func (pc *Collection) jsonPatterns() []jsonPattern {
+	entries := make([]jsonPattern, 0, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		entries = append(entries, newJSONPattern(i, pc.patterns[i]))
+	}
+	return entries
+}
+
+// сохраняет свойства фигуры рядом с её изображением: a/7.png - a/7.json
+func saveSidecar(p *Pattern, index int, imagePath string) error {
+	data, err := json.MarshalIndent(newJSONPattern(index, p), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(strings.TrimSuffix(imagePath, filepath.Ext(imagePath))+".json", data, 0644)
+}
+
+func (pc *Collection) SaveJSON(path string) error {
+	data, err := json.MarshalIndent(pc.jsonPatterns(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// канонические формы фигур из сохранённого JSON
+func LoadKnown(path string) (map[string]bool, error) {
+	pc, err := loadJSON(path)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		known[pc.patterns[i].getCanonical()] = true
+	}
+	return known, nil
+}
+
+func loadJSON(path string) (*Collection, error) {
+	var entries []jsonPattern
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pc := NewCollection()
+	for i := 0; i < len(entries); i++ {
+		p, err := entries[i].toPattern()
+		if err != nil {
+			return nil, fmt.Errorf("%s: фигура %d: %w", path, i, err)
+		}
+		pc.patterns = append(pc.patterns, p)
+	}
+	return pc, nil
+}
+
+func (pc *Collection) SaveCSV(path string) error {
+	var sorted []Triangle
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"pattern_index", "x", "y", "z"})
+	for i := 0; i < len(pc.patterns); i++ {
+		sorted = pc.patterns[i].getSortedTriangles()
+		for j := 0; j < len(sorted); j++ {
+			w.Write([]string{
+				strconv.Itoa(i),
+				strconv.Itoa(sorted[j].x),
+				strconv.Itoa(sorted[j].y),
+				strconv.Itoa(sorted[j].z),
+			})
+		}
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (pc *Collection) SaveText(path string) error {
+	var sb strings.Builder
+	var sorted []Triangle
+	for i := 0; i < len(pc.patterns); i++ {
+		sorted = pc.patterns[i].getSortedTriangles()
+		for j := 0; j < len(sorted); j++ {
+			if j > 0 {
+				sb.WriteByte(' ')
+			}
+			fmt.Fprintf(&sb, "%d,%d,%d", sorted[j].x, sorted[j].y, sorted[j].z)
+		}
+		sb.WriteByte('\n')
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// текстовый формат: по фигуре в строке, треугольники "x,y,z" через пробел,
+// пустые строки и строки с # пропускаются
+func loadText(path string) (*Collection, error) {
+	var p *Pattern
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pc := NewCollection()
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err = ParsePattern(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		pc.patterns = append(pc.patterns, p)
+	}
+	return pc, nil
+}
+
+// сводка по фигурам: по строке на фигуру с её размерами и свойствами
+func (pc *Collection) SaveSummary(path string) error {
+	var p *Pattern
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"pattern_index", "triangles", "perimeter", "width", "height", "symmetry", "holes"})
+	for i := 0; i < len(pc.patterns); i++ {
+		p = pc.patterns[i]
+		w.Write([]string{
+			strconv.Itoa(i),
+			strconv.Itoa(p.Len()),
+			strconv.Itoa(p.Perimeter()),
+			strconv.Itoa(p.Width()),
+			strconv.FormatFloat(p.Height(), 'f', -1, 64),
+			p.symmetryGroup(),
+			strconv.FormatBool(p.hasHoles()),
+		})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func loadCSV(path string) (*Collection, error) {
+	var coords [4]int
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	pc := NewCollection()
+	for i := 1; i < len(records); i++ {
+		if len(records[i]) != 4 {
+			return nil, fmt.Errorf("%s:%d: ожидается 4 столбца", path, i+1)
+		}
+		for j := 0; j < 4; j++ {
+			coords[j], err = strconv.Atoi(records[i][j])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+			}
+		}
+		if coords[0] < 0 || coords[0] > len(pc.patterns) {
+			return nil, fmt.Errorf("%s:%d: неверный номер фигуры %d", path, i+1, coords[0])
+		}
+		if coords[0] == len(pc.patterns) {
+			pc.patterns = append(pc.patterns, NewPattern())
+		}
+		t, err := NewTriangle(coords[1], coords[2], coords[3])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		pc.patterns[coords[0]].addTriangle(t)
+	}
+	for i := 0; i < len(pc.patterns); i++ {
+		if err = validatePattern(pc.patterns[i]); err != nil {
+			return nil, fmt.Errorf("%s: фигура %d: %w", path, i, err)
+		}
+	}
+	return pc, nil
+}
+
+type manifestEntry struct {
+	Filename  string  `json:"filename"`
+	Hash      string  `json:"hash"`
+	Triangles int     `json:"triangles"`
+	Perimeter int     `json:"perimeter"`
+	Area      float64 `json:"area"`
+	Symmetry  string  `json:"symmetry"`
+	Holes     bool    `json:"holes"`
+}
+
+func (pc *Collection) SaveManifest(path string, filenames []string) error {
+	var p *Pattern
+	entries := make([]manifestEntry, 0, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		p = pc.patterns[i]
+		entries = append(entries, manifestEntry{
+			Filename:  filenames[i],
+			Hash:      p.Key(),
+			Triangles: p.Len(),
+			Perimeter: p.Perimeter(),
+			Area:      float64(p.Len()) * math.Sqrt(3) / 4,
+			Symmetry:  p.symmetryGroup(),
+			Holes:     p.hasHoles(),
+		})
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func countPatterns(numTriangles int) int {
+	pc := NewCollection()
+	for range pc.generatePatternsStream(context.Background(), numTriangles, NewPattern()) {
+	}
+	return pc.stats.accepted
+}
+
+func CountsBySize(minTriangles, maxTriangles int) []int {
+	counts := make([]int, 0, max(maxTriangles-minTriangles+1, 0))
+	for n := minTriangles; n <= maxTriangles; n++ {
+		counts = append(counts, countPatterns(n))
+	}
+	return counts
+}
+
+const estimateMaxTriangles = 8
+const nodesEstimateMaxTriangles = 7
+const bytesPerTriangle = 100
+
+// оценка числа вершин дерева перебора для numTriangles треугольников
+// без затравки: малые размеры перебираются, дальше отношение соседних
+// размеров растёт так же, как между двумя последними посчитанными
+func EstimateNodes(numTriangles int) float64 {
+	var ratio, prevRatio float64
+	lastSize := min(numTriangles, nodesEstimateMaxTriangles)
+	nodes := make([]float64, 0, lastSize)
+	for n := 1; n <= lastSize; n++ {
+		pc := NewCollection()
+		pc.generatePatterns(n, NewPattern())
+		nodes = append(nodes, float64(pc.stats.nodes))
+	}
+	if numTriangles <= lastSize {
+		return nodes[lastSize-1]
+	}
+	ratio = nodes[lastSize-1] / nodes[lastSize-2]
+	prevRatio = nodes[lastSize-2] / nodes[lastSize-3]
+	estimate := nodes[lastSize-1]
+	for n := lastSize + 1; n <= numTriangles; n++ {
+		ratio, prevRatio = ratio*ratio/prevRatio, ratio
+		estimate *= ratio
+	}
+	return estimate
+}
+
+// EstimateRun оценивает число фигур, время и память для каждого размера
+// от minTriangles до maxTriangles: размеры до estimateMaxTriangles
+// перебираются, большие экстраполируются
+func EstimateRun(minTriangles, maxTriangles int) ([]string, error) {
+	var start time.Time
+	var countRatio, timeRatio, count, seconds float64
+	if minTriangles < 1 || maxTriangles < minTriangles {
+		return nil, fmt.Errorf("неверный диапазон размеров %d-%d", minTriangles, maxTriangles)
+	}
+	lastSize := min(maxTriangles, estimateMaxTriangles)
+	counts := make([]float64, 0, lastSize)
+	durations := make([]float64, 0, lastSize)
+	for n := 1; n <= lastSize; n++ {
+		start = time.Now()
+		counts = append(counts, float64(countPatterns(n)))
+		durations = append(durations, time.Since(start).Seconds())
+	}
+	// большие размеры экстраполируются по двум последним перебранным
+	if maxTriangles > lastSize {
+		countRatio = counts[lastSize-1] / counts[lastSize-2]
+		timeRatio = max(durations[lastSize-1]/max(durations[lastSize-2], 1e-9), countRatio)
+	}
+	estimates := make([]string, 0, maxTriangles-minTriangles+1)
+	for n := minTriangles; n <= maxTriangles; n++ {
+		if n <= lastSize {
+			count = counts[n-1]
+			seconds = durations[n-1]
+		} else {
+			count = counts[lastSize-1] * math.Pow(countRatio, float64(n-lastSize))
+			seconds = durations[lastSize-1] * math.Pow(timeRatio, float64(n-lastSize))
+		}
+		estimates = append(estimates, fmt.Sprintf("%d: около %.0f фигур, время генерации около %v, память около %.1f МБ",
+			n, count, time.Duration(seconds*float64(time.Second)).Round(time.Second),
+			count*float64(n*bytesPerTriangle)/(1<<20)))
+	}
+	return estimates, nil
+}
+
+// GenerateRange перебирает фигуры каждого размера от minTriangles
+// до maxTriangles, продолжая затравку seed. С recordSteps у фигур
+// записываются шаги построения, нужные для анимации роста. Сообщения
+// о контрольных точках из checkpointDir пишутся в log, если он не nil.
+func GenerateRange(ctx context.Context, minTriangles, maxTriangles int, seed *Pattern, placements, recordSteps bool, known map[string]bool, workers int, checkpointDir string, log io.Writer, onProgress func(numTriangles, nodes, accepted int)) []*Collection {
+	var reportSize func(nodes, accepted int)
+	var err error
+	collections := make([]*Collection, 0, maxTriangles-minTriangles+1)
+	for n := minTriangles; n <= maxTriangles; n++ {
+		reportSize = nil
+		if onProgress != nil {
+			numTriangles := n
+			reportSize = func(nodes, accepted int) {
+				onProgress(numTriangles, nodes, accepted)
+			}
+		}
+		pc := NewCollection()
+		pc.placements = placements
+		pc.known = known
+		pc.recordSteps = recordSteps
+		pc.onProgress = reportSize
+		if checkpointDir != "" {
+			seed.validateHash()
+			pc.checkpointDir = checkpointDir
+			pc.log = log
+			pc.checkpointSize = n
+			pc.checkpointSeed = formatHash(seed.patternHash)
+		}
+		if (workers > 1 || checkpointDir != "") && n-seed.Len() >= 3 {
+			err = pc.generatePatternsParallel(ctx, n-seed.Len(), seed.getCopy(), workers)
+		} else {
+			err = pc.generatePatternsCtx(ctx, n-seed.Len(), seed.getCopy())
+		}
+		pc.sortCanonical()
+		collections = append(collections, pc)
+		if err != nil {
+			break
+		}
+	}
+	return collections
+}
+
+func ParseRange(s string) (int, int, error) {
+	var minTriangles, maxTriangles int
+	var err error
+	bounds := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	minTriangles, err = strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	maxTriangles = minTriangles
+	if len(bounds) == 2 {
+		maxTriangles, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return minTriangles, maxTriangles, nil
+}
+
+// выполняет fn для номеров от 0 до count-1 в jobs потоках;
+// возвращает ошибки в порядке их появления
+func forEachParallel(count, jobs int, fn func(i int) error) []error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	indices := make(chan int)
+	for w := 0; w < max(jobs, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				err := fn(i)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return errs
+}
+
+// наибольший номер фигуры в списке номеров, с запасом больше числа фигур
+// любого допустимого размера
+const maxIndex = 1 << 24
+
+// ParseIndices разбирает список номеров фигур вида "3,7,100-120";
+// возвращает номера по возрастанию без повторов
+func ParseIndices(s string) ([]int, error) {
+	var first, last int
+	var err error
+	seen := make(map[int]bool)
+	parts := strings.Split(s, ",")
+	for i := 0; i < len(parts); i++ {
+		if strings.TrimSpace(parts[i]) == "" {
+			continue
+		}
+		first, last, err = ParseRange(parts[i])
+		if err != nil || first < 0 || first > last || last >= maxIndex {
+			return nil, fmt.Errorf("неверный номер или диапазон номеров %q", strings.TrimSpace(parts[i]))
+		}
+		for index := first; index <= last; index++ {
+			seen[index] = true
+		}
+	}
+	indices := make([]int, 0, len(seen))
+	for index := range seen {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	return indices, nil
+}
+
+// SelectIndices оставляет из indices номера меньше total (nil - все номера
+// от 0 до total-1) и не больше limit первых из них, если limit > 0
+func SelectIndices(total int, indices []int, limit int) []int {
+	selected := make([]int, 0, total)
+	if indices == nil {
+		for i := 0; i < total; i++ {
+			selected = append(selected, i)
+		}
+	} else {
+		for i := 0; i < len(indices); i++ {
+			if indices[i] < total {
+				selected = append(selected, indices[i])
+			}
+		}
+	}
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+// SavePatterns рисует и сохраняет фигуры коллекции в jobs потоков;
+// selected - номера фигур, которые нужно нарисовать, nil - все.
+// Возвращает имена файлов всех фигур, нарисованных или нет.
+func SavePatterns(dir string, pc *Collection, opts RenderOptions, jobs int, selected []int) ([]string, []error) {
+	filenames := make([]string, len(pc.patterns))
+	for i := 0; i < len(pc.patterns); i++ {
+		filenames[i] = opts.filename(i, pc.patterns[i])
+	}
+	if selected == nil {
+		selected = SelectIndices(len(pc.patterns), nil, 0)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, []error{err}
+	}
+	errs := forEachParallel(len(selected), jobs, func(k int) error {
+		i := selected[k]
+		path := filepath.Join(dir, filenames[i])
+		err := savePattern(pc.patterns[i], i, path, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		return nil
+	})
+	return filenames, errs
+}
+
+type streamedPattern struct {
+	index int
+	p     *Pattern
+}
+
+// StreamPatterns сохраняет фигуры по мере нахождения, рисуя их в jobs
+// потоков параллельно с генерацией; на первой ошибке записи генерация
+// останавливается
+func StreamPatterns(ctx context.Context, dir string, numTriangles int, seed *Pattern, known map[string]bool, opts RenderOptions, jobs int) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var saveErr error
+	i := 0
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	genCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	found := make(chan streamedPattern, max(jobs, 1))
+	for w := 0; w < max(jobs, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sp := range found {
+				name := filepath.Join(dir, opts.filename(sp.index, sp.p))
+				err := savePattern(sp.p, sp.index, name, opts)
+				if err != nil {
+					mu.Lock()
+					if saveErr == nil {
+						saveErr = fmt.Errorf("%s: %v", name, err)
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+	pc := NewCollection()
+	pc.known = known
+	for p := range pc.generatePatternsStream(genCtx, numTriangles-seed.Len(), seed.getCopy()) {
+		found <- streamedPattern{index: i, p: p}
+		i++
+	}
+	close(found)
+	wg.Wait()
+	return saveErr
+}
+
+func DrawSingle(coords, path string, opts RenderOptions) error {
+	p, err := ParsePattern(coords)
+	if err != nil {
+		return err
+	}
+	if p.Len() == 0 {
+		return fmt.Errorf("не заданы координаты треугольников")
+	}
+	if !p.IsConnected() {
+		return fmt.Errorf("треугольники должны быть связаны сторонами")
+	}
+	if path == "" {
+		path = "pattern." + opts.extension()
+	}
+	return savePattern(p.getCentered(), 0, path, opts)
+}
+
+// загружает фигуры из файла, формат определяется по расширению
+func loadPatterns(path string) (*Collection, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return loadCSV(path)
+	case ".json":
+		return loadJSON(path)
+	}
+	return loadText(path)
+}
+
+// RenderLoaded сохраняет изображения фигур из файла path в dir;
+// о пропущенных неверных фигурах сообщается в log, если он не nil
+func RenderLoaded(path, dir string, opts RenderOptions, jobs int, log io.Writer) error {
+	var valid []int
+	skipped := 0
+	pc, err := loadPatterns(path)
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		dir = "."
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(pc.patterns); i++ {
+		err = validatePattern(pc.patterns[i])
+		if err != nil {
+			logf(log, "фигура %d пропущена: %v\n", i, err)
+			skipped++
+			continue
+		}
+		valid = append(valid, i)
+	}
+	errs := forEachParallel(len(valid), jobs, func(k int) error {
+		i := valid[k]
+		name := filepath.Join(dir, opts.filename(i, pc.patterns[i]))
+		err := savePattern(pc.patterns[i].getCentered(), i, name, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	if skipped > 0 {
+		return fmt.Errorf("пропущено фигур с ошибками: %d из %d", skipped, len(pc.patterns))
+	}
+	return nil
+}
+
+func SaveComparison(list, path string, opts RenderOptions) error {
+	var p *Pattern
+	var err error
+	items := strings.Split(list, "|")
+	ps := make([]*Pattern, 0, len(items))
+	for i := 0; i < len(items); i++ {
+		p, err = ParsePattern(items[i])
+		if err != nil {
+			return err
+		}
+		if p.Len() == 0 || !p.IsConnected() {
+			return fmt.Errorf("фигура %d должна быть непустой и связной", i)
+		}
+		ps = append(ps, p.getCentered())
+	}
+	if path == "" {
+		path = "comparison.png"
+	}
+	return renderComparison(ps, opts).SavePNG(path)
+}
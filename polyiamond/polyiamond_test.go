@@ -0,0 +1,913 @@
+package polyiamond
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// фигуры для проверок в записи ParsePattern
+const (
+	single  = "0,1,0"
+	diamond = "0,0,-1 0,1,0"
+	// шестиугольник из шести треугольников вокруг общей вершины
+	hexagon = "-1,0,0 0,-1,0 0,0,-1 0,0,1 0,1,0 1,0,0"
+	// прямая полоса из четырёх треугольников
+	strip4 = "-1,0,0 0,0,-1 0,1,0 1,1,-1"
+	// фигура из пяти треугольников без осей симметрии
+	chiral5 = "-1,0,0 -1,1,-1 0,0,-1 0,1,0 1,0,0"
+	// полоса из трёх треугольников: нечётный размах по x и по y
+	strip3 = "0,0,-1 0,1,0 1,1,-1"
+)
+
+var update = flag.Bool("update", false, "перезаписать эталонные изображения в testdata")
+
+// число свободных фигур из 1, 2, ... треугольников (OEIS A000577)
+var freeCounts = []int{1, 1, 1, 3, 4, 12, 24, 66, 160, 448, 1186, 3334, 9235, 26166}
+
+func mustParse(t testing.TB, s string) *Pattern {
+	t.Helper()
+	p, err := ParsePattern(s)
+	if err != nil {
+		t.Fatalf("%q: %v", s, err)
+	}
+	return p
+}
+
+// фигуры из n треугольников; перебор для каждого n выполняется один раз
+var generatedCache = make(map[int][]*Pattern)
+
+func generated(n int) []*Pattern {
+	if _, ok := generatedCache[n]; !ok {
+		pc := NewCollection()
+		pc.generatePatterns(n, NewPattern())
+		generatedCache[n] = pc.patterns
+	}
+	return generatedCache[n]
+}
+
+func lineLength(l line) float64 {
+	return math.Hypot(l.x2-l.x1, l.y2-l.y1)
+}
+
+func TestBoundaryEdges(t *testing.T) {
+	var edges []line
+	cases := []struct {
+		shape string
+		edges int
+	}{
+		{single, 3},
+		{diamond, 4},
+		{hexagon, 6},
+		{strip4, 6},
+	}
+	side := lineLength(mustParse(t, single).boundaryEdges()[0])
+	for i := 0; i < len(cases); i++ {
+		edges = mustParse(t, cases[i].shape).boundaryEdges()
+		if len(edges) != cases[i].edges {
+			t.Errorf("%q: сторон на границе %d, ожидалось %d", cases[i].shape, len(edges), cases[i].edges)
+		}
+		for j := 0; j < len(edges); j++ {
+			if !edges[j].bold || math.Abs(lineLength(edges[j])-side) > 1e-9 {
+				t.Errorf("%q: сторона %d: %+v не похожа на сторону треугольника", cases[i].shape, j, edges[j])
+			}
+		}
+	}
+}
+
+// проверяется и с go test -race
+func TestAddUniqueConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	const goroutines = 16
+	ps := generated(6)
+	pc := NewCollection()
+	added := 0
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var variants []*Pattern
+			for i := 0; i < len(ps); i++ {
+				variants = ps[(i+g)%len(ps)].allVariants()
+				for j := 0; j < len(variants); j++ {
+					if pc.addUnique(variants[j].getShifted(g, 1+j%3)) {
+						mu.Lock()
+						added++
+						mu.Unlock()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if added != len(ps) || len(pc.patterns) != len(ps) {
+		t.Errorf("добавлено %d, в наборе %d, ожидалось %d", added, len(pc.patterns), len(ps))
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	pc := NewCollection()
+	pc.patterns = generated(7)
+	path := filepath.Join(t.TempDir(), "patterns.csv")
+	if err := pc.SaveCSV(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.patterns) != len(pc.patterns) {
+		t.Fatalf("прочитано фигур %d, записано %d", len(loaded.patterns), len(pc.patterns))
+	}
+	for i := 0; i < len(pc.patterns); i++ {
+		if !loaded.patterns[i].isEqual(pc.patterns[i]) {
+			t.Errorf("фигура %d: прочитана %s, записана %s", i, loaded.patterns[i].Encode(), pc.patterns[i].Encode())
+		}
+	}
+}
+
+func TestLoadRejectsInvalid(t *testing.T) {
+	var err error
+	dir := t.TempDir()
+	files := []struct {
+		name, data string
+	}{
+		// координата вне допустимых переполнила бы упаковку и совпала
+		// с хэшем другой фигуры
+		{"far.json", `[{"triangles":[{"x":0,"y":1,"z":0}]},{"triangles":[{"x":9000000,"y":-9000000,"z":1}]}]`},
+		{"empty.json", `[{"triangles":[]}]`},
+		{"disconnected.json", `[{"triangles":[{"x":0,"y":1,"z":0},{"x":3,"y":1,"z":-3}]}]`},
+		{"far.csv", "pattern_index,x,y,z\n0,9000000,-9000000,1\n"},
+		{"disconnected.csv", "pattern_index,x,y,z\n0,0,1,0\n0,3,1,-3\n"},
+		{"repeated.csv", "pattern_index,x,y,z\n0,0,1,0\n0,0,1,0\n"},
+	}
+	for i := 0; i < len(files); i++ {
+		path := filepath.Join(dir, files[i].name)
+		if err = os.WriteFile(path, []byte(files[i].data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = loadPatterns(path); err == nil {
+			t.Errorf("%s: неверная фигура прочитана без ошибки", files[i].name)
+		}
+	}
+	if _, err = LoadKnown(filepath.Join(dir, "far.json")); err == nil {
+		t.Error("LoadKnown принял координату вне допустимых")
+	}
+
+	pc := NewCollection()
+	pc.patterns = generated(6)
+	path := filepath.Join(dir, "valid.json")
+	if err = pc.SaveJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	known, err := LoadKnown(path)
+	if err != nil || len(known) != len(pc.patterns) {
+		t.Errorf("LoadKnown: форм %d, ожидалось %d, %v", len(known), len(pc.patterns), err)
+	}
+}
+
+func TestChirality(t *testing.T) {
+	p := mustParse(t, chiral5)
+	mirror := p.getReflected(2).getShifted(3, 1)
+	rotated := p.getRotated(4).getShifted(-2, 3)
+	if !p.isChiral() {
+		t.Error("фигура без осей симметрии не признана хиральной")
+	}
+	if !p.isEqual(mirror) || p.isEqualChiral(mirror) {
+		t.Error("зеркальная копия должна совпадать только с отражениями")
+	}
+	if !p.isEqualChiral(rotated) {
+		t.Error("повёрнутая копия не совпала без отражений")
+	}
+	if mustParse(t, hexagon).isChiral() {
+		t.Error("шестиугольник признан хиральным")
+	}
+	// полоса - параллелограмм: переходит в себя поворотом, но не отражением
+	if !mustParse(t, strip4).isChiral() {
+		t.Error("полоса из четырёх треугольников не признана хиральной")
+	}
+}
+
+// односторонних фигур столько, сколько свободных, и ещё по одной
+// на каждую хиральную: её отражение считается отдельно
+func TestOneSidedCounts(t *testing.T) {
+	var ps []*Pattern
+	var chiral int
+	oneSided := map[int]int{4: 4, 5: 6, 6: 19, 7: 43}
+	for n := 4; n <= 7; n++ {
+		ps = generated(n)
+		chiral = 0
+		for i := 0; i < len(ps); i++ {
+			if ps[i].isChiral() {
+				chiral++
+			}
+		}
+		if len(ps)+chiral != oneSided[n] {
+			t.Errorf("%d треугольников: односторонних фигур %d, ожидалось %d", n, len(ps)+chiral, oneSided[n])
+		}
+	}
+}
+
+func TestSeedCounts(t *testing.T) {
+	var collections []*Collection
+	cases := []struct {
+		seed   string
+		counts []int
+	}{
+		// каждая фигура содержит треугольник, а из двух и более - ромб
+		{"", freeCounts[:8]},
+		{single, freeCounts[:8]},
+		{diamond, freeCounts[1:8]},
+		// к шестиугольнику любой треугольник пристраивается одинаково
+		{hexagon, []int{1, 1}},
+	}
+	for i := 0; i < len(cases); i++ {
+		seed := NewPattern()
+		if cases[i].seed != "" {
+			seed = mustParse(t, cases[i].seed)
+		}
+		minTriangles := max(seed.Len(), 1)
+		collections = GenerateRange(context.Background(), minTriangles, minTriangles+len(cases[i].counts)-1, seed, false, false, nil, 1, "", nil, nil)
+		for j := 0; j < len(collections); j++ {
+			if len(collections[j].patterns) != cases[i].counts[j] {
+				t.Errorf("затравка %q, %d треугольников: фигур %d, ожидалось %d", cases[i].seed, minTriangles+j, len(collections[j].patterns), cases[i].counts[j])
+			}
+		}
+	}
+}
+
+// вариантов столько, во сколько раз группа симметрий фигуры меньше D6
+func TestAllVariants(t *testing.T) {
+	var p *Pattern
+	var variants []*Pattern
+	cases := []struct {
+		shape    string
+		variants int
+	}{
+		{hexagon, 1},
+		{single, 2},
+		{strip4, 6},
+		{chiral5, 12},
+	}
+	for i := 0; i < len(cases); i++ {
+		p = mustParse(t, cases[i].shape)
+		variants = p.allVariants()
+		if len(variants) != cases[i].variants {
+			t.Errorf("%q (%s): вариантов %d, ожидалось %d", cases[i].shape, p.symmetryGroup(), len(variants), cases[i].variants)
+		}
+		for j := 0; j < len(variants); j++ {
+			if !variants[j].isEqual(p) {
+				t.Errorf("%q: вариант %s не совпадает с фигурой", cases[i].shape, variants[j].Encode())
+			}
+		}
+	}
+}
+
+func TestLoadedConnectivity(t *testing.T) {
+	lines := []struct {
+		shape     string
+		connected bool
+	}{
+		{hexagon, true},
+		{chiral5, true},
+		// два треугольника далеко друг от друга
+		{"0,1,0 3,1,-3", false},
+		// общая вершина не связывает фигуру
+		{"0,1,0 1,0,0", false},
+	}
+	text := ""
+	for i := 0; i < len(lines); i++ {
+		text += lines[i].shape + "\n"
+	}
+	path := filepath.Join(t.TempDir(), "patterns.txt")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pc, err := loadText(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(lines); i++ {
+		if pc.patterns[i].IsConnected() != lines[i].connected {
+			t.Errorf("%q: IsConnected() = %v", lines[i].shape, !lines[i].connected)
+		}
+		if err = validatePattern(pc.patterns[i]); (err == nil) != lines[i].connected {
+			t.Errorf("%q: validatePattern: %v", lines[i].shape, err)
+		}
+	}
+}
+
+func TestPerimeter(t *testing.T) {
+	var ps []*Pattern
+	cases := []struct {
+		shape     string
+		perimeter int
+	}{
+		{single, 3},
+		{strip4, 6},
+		{hexagon, 6},
+		{chiral5, 7},
+	}
+	for i := 0; i < len(cases); i++ {
+		p := mustParse(t, cases[i].shape)
+		if p.boundaryEdgeCount() != cases[i].perimeter || p.Perimeter() != cases[i].perimeter {
+			t.Errorf("%q: сторон на границе %d, периметр %d, ожидалось %d", cases[i].shape, p.boundaryEdgeCount(), p.Perimeter(), cases[i].perimeter)
+		}
+	}
+	// каждая внутренняя сторона принадлежит двум треугольникам,
+	// поэтому периметр 3n - 2·(число внутренних сторон) той же чётности, что и n
+	ps = generated(8)
+	for i := 0; i < len(ps); i++ {
+		if ps[i].Perimeter()%2 != 0 || ps[i].Perimeter() != len(ps[i].boundaryEdges()) {
+			t.Errorf("%s: периметр %d, сторон на границе %d", ps[i].Encode(), ps[i].Perimeter(), len(ps[i].boundaryEdges()))
+		}
+	}
+}
+
+// у треугольника одна сторона вертикальна; "верхний" направлен
+// противолежащей вершиной влево, "нижний" - вправо
+func TestIsUpward(t *testing.T) {
+	var v [3][2]float64
+	var apex, side float64
+	triangles := []Triangle{{0, 1, 0}, {0, 0, -1}, {1, 0, 0}, {-1, 0, 0}, {2, 1, -2}, {3, -1, -1}, {-2, 5, -4}, {-4, 2, 3}}
+	for i := 0; i < len(triangles); i++ {
+		v = triangles[i].vertices()
+		apex = math.NaN()
+		for j := 0; j < 3; j++ {
+			if math.Abs(v[(j+1)%3][0]-v[(j+2)%3][0]) < 1e-9 {
+				apex, side = v[j][0], v[(j+1)%3][0]
+			}
+		}
+		if math.IsNaN(apex) {
+			t.Errorf("%v: нет вертикальной стороны", triangles[i])
+			continue
+		}
+		if triangles[i].isUpward() != (apex < side) {
+			t.Errorf("%v: isUpward() = %v, вершина %.3f, сторона %.3f", triangles[i], triangles[i].isUpward(), apex, side)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	var streamed []*Pattern
+	pc := NewCollection()
+	for p := range pc.generatePatternsStream(context.Background(), 8, NewPattern()) {
+		streamed = append(streamed, p)
+	}
+	ps := generated(8)
+	if len(streamed) != len(ps) {
+		t.Fatalf("получено фигур %d, в наборе %d", len(streamed), len(ps))
+	}
+	for i := 0; i < len(ps); i++ {
+		if streamed[i].Key() != ps[i].Key() {
+			t.Errorf("фигура %d: получена %s, в наборе %s", i, streamed[i].Encode(), ps[i].Encode())
+		}
+	}
+
+	// после отмены канал закрывается, даже если его не дочитали
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := NewCollection().generatePatternsStream(ctx, 12, NewPattern())
+	<-ch
+	cancel()
+	for range ch {
+	}
+}
+
+// a+b+c = 2 у "верхних" треугольников и 1 у "нижних"; переход через
+// сторону меняет ровно одну кубическую координату на 1
+func TestCubeRoundTrip(t *testing.T) {
+	var a, b, c, na, nb, nc, sum int
+	var back, neighbour *Triangle
+	var err error
+	for x := -4; x <= 4; x++ {
+		for y := -4; y <= 4; y++ {
+			for _, z := range []int{1 - x - y, -1 - x - y} {
+				tr := newTriangle(x, y, z)
+				a, b, c = tr.toCube()
+				sum = 1
+				if tr.isUpward() {
+					sum = 2
+				}
+				if a+b+c != sum {
+					t.Errorf("%v: кубические координаты %d,%d,%d, сумма должна быть %d", *tr, a, b, c, sum)
+				}
+				back, err = triangleFromCube(a, b, c)
+				if err != nil || !back.isEqual(tr) {
+					t.Errorf("%v: обратно получено %v, %v", *tr, back, err)
+				}
+				for axis := 1; axis <= 3; axis++ {
+					neighbour = tr.getNeighbour(axis)
+					na, nb, nc = neighbour.toCube()
+					if abs(na-a)+abs(nb-b)+abs(nc-c) != 1 {
+						t.Errorf("%v и сосед %v: кубические координаты %d,%d,%d и %d,%d,%d", *tr, *neighbour, a, b, c, na, nb, nc)
+					}
+				}
+			}
+		}
+	}
+	if _, err = triangleFromCube(1, 1, 1); err == nil {
+		t.Error("кубические координаты с суммой 3 приняты")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	var ps []*Pattern
+	var decoded *Pattern
+	var code string
+	var err error
+	for n := 1; n <= 8; n++ {
+		ps = generated(n)
+		for i := 0; i < len(ps); i++ {
+			code = ps[i].Encode()
+			decoded, err = ParsePattern(code)
+			if err != nil {
+				t.Errorf("%d треугольников, фигура %d: %s не разобрана: %v", n, i, code, err)
+				continue
+			}
+			if decoded.Key() != ps[i].Key() || !decoded.isEqual(ps[i]) {
+				t.Errorf("%d треугольников, фигура %d: из %s получена %s", n, i, code, decoded.Encode())
+			}
+		}
+	}
+	malformed := []string{
+		"0",
+		"0-0",
+		"4",
+		"1-0-2",
+		// треугольники касаются только вершиной
+		"1-02",
+		strings.Repeat("0", maxCoord+1) + "3",
+	}
+	for i := 0; i < len(malformed); i++ {
+		if _, err = decodePattern(malformed[i]); err == nil {
+			t.Errorf("неверная краткая запись %.20q принята", malformed[i])
+		}
+	}
+}
+
+func TestCountsBySize(t *testing.T) {
+	counts := CountsBySize(1, 10)
+	if !slices.Equal(counts, freeCounts[:10]) {
+		t.Errorf("CountsBySize(1, 10) = %v, ожидалось %v", counts, freeCounts[:10])
+	}
+	if counts = CountsBySize(5, 7); !slices.Equal(counts, freeCounts[4:7]) {
+		t.Errorf("CountsBySize(5, 7) = %v, ожидалось %v", counts, freeCounts[4:7])
+	}
+}
+
+func TestEstimateRun(t *testing.T) {
+	estimates, err := EstimateRun(1, 1)
+	if err != nil || len(estimates) != 1 || !strings.HasPrefix(estimates[0], "1: около 1 фигур") {
+		t.Errorf("EstimateRun(1, 1) = %q, %v", estimates, err)
+	}
+	estimates, err = EstimateRun(7, 10)
+	if err != nil || len(estimates) != 4 || !strings.HasPrefix(estimates[0], "7: около 24 фигур") {
+		t.Errorf("EstimateRun(7, 10) = %q, %v", estimates, err)
+	}
+	for _, bounds := range [][2]int{{0, 3}, {-2, 1}, {5, 4}} {
+		if _, err = EstimateRun(bounds[0], bounds[1]); err == nil {
+			t.Errorf("EstimateRun(%d, %d): диапазон принят", bounds[0], bounds[1])
+		}
+	}
+}
+
+func TestFrontier(t *testing.T) {
+	var p, grown *Pattern
+	var cells []*Triangle
+	cases := []struct {
+		shape    string
+		frontier int
+	}{
+		{single, 3},
+		{diamond, 4},
+		{strip3, 5},
+		{hexagon, 6},
+		// шестиугольник без треугольника: пустая клетка примыкает к двум
+		// треугольникам, но считается один раз
+		{"-1,0,0 0,-1,0 0,0,-1 0,0,1 1,0,0", 6},
+	}
+	for i := 0; i < len(cases); i++ {
+		p = mustParse(t, cases[i].shape)
+		cells = p.frontier()
+		if len(cells) != cases[i].frontier {
+			t.Errorf("%q: клеток на границе %d, ожидалось %d", cases[i].shape, len(cells), cases[i].frontier)
+		}
+		for j := 0; j < len(cells); j++ {
+			grown = p.getCopy()
+			grown.addTriangle(cells[j])
+			if p.contains(cells[j]) || !grown.IsConnected() {
+				t.Errorf("%q: клетка %v не примыкает к фигуре снаружи", cases[i].shape, *cells[j])
+			}
+		}
+	}
+}
+
+// связная фигура из n треугольников: к ней по одному добавляются
+// случайные клетки с границы
+func randomPattern(rng *rand.Rand, n int) *Pattern {
+	var cells []*Triangle
+	p := NewPattern()
+	p.addTriangle(newTriangle(0, 1, 0))
+	for p.Len() < n {
+		cells = p.frontier()
+		p.addTriangle(cells[rng.IntN(len(cells))])
+	}
+	return p
+}
+
+// случайный поворот, отражение и сдвиг
+func randomTransform(rng *rand.Rand, p *Pattern) *Pattern {
+	return p.getRotated(rng.IntN(6)).getReflected(rng.IntN(4)).getShifted(rng.IntN(21)-10, 1+rng.IntN(3)).getShifted(rng.IntN(21)-10, 1+rng.IntN(3))
+}
+
+// a.isEqual(b) тогда и только тогда, когда a.Key() == b.Key()
+func TestKeyMatchesIsEqual(t *testing.T) {
+	var ps []*Pattern
+	var keys []string
+	var p, q *Pattern
+	rng := rand.New(rand.NewPCG(1, 2))
+	for n := 5; n <= 9; n++ {
+		ps = ps[:0]
+		for i := 0; i < 60; i++ {
+			p = randomPattern(rng, n)
+			q = randomTransform(rng, p)
+			if p.Key() != q.Key() || !p.isEqual(q) {
+				t.Errorf("%s и её копия %s: ключи %q и %q, isEqual %v", p.Encode(), q.Encode(), p.Key(), q.Key(), p.isEqual(q))
+			}
+			ps = append(ps, p, q)
+		}
+		keys = keys[:0]
+		for i := 0; i < len(ps); i++ {
+			keys = append(keys, ps[i].Key())
+		}
+		for i := 0; i < len(ps); i++ {
+			for j := i + 1; j < len(ps); j++ {
+				if (keys[i] == keys[j]) != ps[i].isEqual(ps[j]) {
+					t.Errorf("%s и %s: совпадение ключей %v, isEqual %v", ps[i].Encode(), ps[j].Encode(), keys[i] == keys[j], ps[i].isEqual(ps[j]))
+				}
+			}
+		}
+	}
+}
+
+// фигура у края допустимых координат проходит полный цикл поворотов,
+// отражений и выравниваний без переполнения и остаётся на сетке
+func TestLargeCoordinates(t *testing.T) {
+	var x, y, z int
+	var far, q *Pattern
+	small := mustParse(t, chiral5)
+	shifted := small.getShifted(maxCoord-2, 1)
+	far = NewPattern()
+	for i := 0; i < len(shifted.triangles); i++ {
+		x, y, z = shifted.triangles[i].Coords()
+		tr, err := NewTriangle(x, y, z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		far.addTriangle(tr)
+	}
+	for angle := 0; angle < 6; angle++ {
+		for axis := 0; axis <= 3; axis++ {
+			for freeAxis := 1; freeAxis <= 3; freeAxis++ {
+				q = far.getRotated(angle).getReflected(axis).getAligned(freeAxis)
+				if err := validatePattern(q); err != nil {
+					t.Errorf("поворот %d, отражение %d, выравнивание %d: %v", angle, axis, freeAxis, err)
+				}
+				if !q.isEqual(small) {
+					t.Errorf("поворот %d, отражение %d, выравнивание %d: %s не совпадает с исходной фигурой", angle, axis, freeAxis, q.Encode())
+				}
+			}
+		}
+	}
+	if far.Key() != small.Key() {
+		t.Errorf("ключ %q, у той же фигуры около начала координат %q", far.Key(), small.Key())
+	}
+	if _, err := NewTriangle(maxCoord+1, -maxCoord, 0); err == nil {
+		t.Errorf("координата %d принята", maxCoord+1)
+	}
+}
+
+// сторона треугольника сетки равна единице
+func TestVertices(t *testing.T) {
+	var v [3][2]float64
+	var x1, y1, x2, y2, d float64
+	triangles := []Triangle{{0, 1, 0}, {0, 0, -1}, {3, -1, -1}, {-2, 5, -4}, {7, -3, -5}}
+	for i := 0; i < len(triangles); i++ {
+		v = triangles[i].vertices()
+		for j := 0; j < 3; j++ {
+			d = math.Hypot(v[j][0]-v[(j+1)%3][0], v[j][1]-v[(j+1)%3][1])
+			if math.Abs(d-1) > 1e-9 {
+				t.Errorf("%v: сторона между вершинами %d и %d длиной %v", triangles[i], j, (j+1)%3, d)
+			}
+		}
+		// концы каждой стороны - вершины треугольника
+		for axis := 1; axis <= 3; axis++ {
+			x1, y1, x2, y2 = triangles[i].getCartesianCoords(axis)
+			if !isVertex(v, x1, y1) || !isVertex(v, x2, y2) {
+				t.Errorf("%v: сторона по оси %d не соединяет вершины %v", triangles[i], axis, v)
+			}
+		}
+	}
+}
+
+func isVertex(v [3][2]float64, x, y float64) bool {
+	for i := 0; i < 3; i++ {
+		if math.Hypot(v[i][0]-x, v[i][1]-y) < 1e-9 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegion(t *testing.T) {
+	var ps []*Pattern
+	const side = 3
+	for n := 1; n <= side*side; n++ {
+		pc := NewCollection()
+		pc.allowed = withinTriangle(side)
+		pc.generatePatterns(n, NewPattern())
+		ps = pc.patterns
+		// до пяти треугольников область вмещает любую фигуру
+		if len(ps) == 0 || (n <= 5 && len(ps) != freeCounts[n-1]) || (n > 5 && len(ps) >= freeCounts[n-1]) {
+			t.Errorf("%d треугольников в треугольнике со стороной %d: фигур %d, всего фигур %d", n, side, len(ps), freeCounts[n-1])
+		}
+		// область вмещает не больше одной фигуры из всех своих клеток
+		if n == side*side && len(ps) != 1 {
+			t.Errorf("фигур из всех %d клеток области %d", n, len(ps))
+		}
+	}
+	// фигуры строятся в самой области, а не в её сдвиге
+	pc := NewCollection()
+	pc.allowed = withinTriangle(side)
+	pc.placements = true
+	pc.generatePatterns(4, NewPattern())
+	for i := 0; i < len(pc.patterns); i++ {
+		for j := 0; j < len(pc.patterns[i].triangles); j++ {
+			if !pc.allowed(&pc.patterns[i].triangles[j]) {
+				t.Errorf("%s выходит за область", pc.patterns[i].Encode())
+			}
+		}
+	}
+}
+
+// число общих вершин двух треугольников в декартовых координатах
+func sharedVertices(a, b *Triangle) int {
+	count := 0
+	va, vb := a.vertices(), b.vertices()
+	for i := 0; i < 3; i++ {
+		if isVertex(vb, va[i][0], va[i][1]) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestVertexNeighbours(t *testing.T) {
+	var neighbours []*Triangle
+	triangles := []Triangle{{0, 1, 0}, {0, 0, -1}, {2, -1, 0}, {-3, 1, 1}}
+	for i := 0; i < len(triangles); i++ {
+		tr := &triangles[i]
+		for axis := 1; axis <= 3; axis++ {
+			if n := sharedVertices(tr, tr.getNeighbour(axis)); n != 2 {
+				t.Errorf("%v: у соседа по оси %d общих вершин %d, ожидалось 2", *tr, axis, n)
+			}
+		}
+		neighbours = tr.getVertexNeighbours()
+		seen := make(map[Triangle]bool)
+		for j := 0; j < len(neighbours); j++ {
+			if n := sharedVertices(tr, neighbours[j]); n != 1 {
+				t.Errorf("%v: у соседа по вершине %v общих вершин %d, ожидалось 1", *tr, *neighbours[j], n)
+			}
+			seen[*neighbours[j]] = true
+		}
+		// вокруг каждой из трёх вершин шесть треугольников: сам, два соседа
+		// по сторонам и три касающихся только вершиной
+		if len(seen) != 9 {
+			t.Errorf("%v: разных соседей по вершине %d, ожидалось 9", *tr, len(seen))
+		}
+	}
+
+	cases := []struct {
+		shape      string
+		vertexOnly bool
+	}{
+		{diamond, false},
+		{hexagon, false},
+		{"0,1,0 1,0,0", true},
+		// ромб и треугольник, касающийся его вершиной
+		{"0,0,-1 0,1,0 -1,1,1", true},
+		{"0,1,0 3,1,-3", false},
+	}
+	for i := 0; i < len(cases); i++ {
+		if mustParse(t, cases[i].shape).isVertexConnectedOnly() != cases[i].vertexOnly {
+			t.Errorf("%q: isVertexConnectedOnly() = %v", cases[i].shape, !cases[i].vertexOnly)
+		}
+	}
+}
+
+func TestMinSize(t *testing.T) {
+	var pimg patternImage
+	var x, y float64
+	const minSize = 400
+	shapes := []*Pattern{
+		mustParse(t, single).getCentered(),
+		randomPattern(rand.New(rand.NewPCG(3, 4)), 16).getCentered(),
+	}
+	for i := 0; i < len(shapes); i++ {
+		pimg = newPatternImage(RenderOptions{MinSize: minSize, Padding: 10})
+		pimg.drawPattern(shapes[i])
+		if pimg.img.Width() < minSize || pimg.img.Height() < minSize {
+			t.Errorf("%d треугольников: холст %dx%d меньше %d", shapes[i].Len(), pimg.img.Width(), pimg.img.Height(), minSize)
+		}
+		// центр фигуры остаётся в центре холста
+		x, y = pimg.toReal(pimg.xCenter, pimg.yCenter)
+		if math.Abs(x-float64(pimg.img.Width())/2) > 1 || math.Abs(y-float64(pimg.img.Height())/2) > 1 {
+			t.Errorf("%d треугольников: центр фигуры в %.1f,%.1f на холсте %dx%d", shapes[i].Len(), x, y, pimg.img.Width(), pimg.img.Height())
+		}
+	}
+}
+
+// сравнивает изображение фигуры с testdata/<name>.png побайтно
+func checkGolden(t *testing.T, name string, p *Pattern, opts RenderOptions) {
+	t.Helper()
+	got, err := renderToBytes(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", name+".png")
+	if *update {
+		if err = os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; эталон создаётся командой go test -update", err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	// полученное изображение остаётся после теста, чтобы его можно было сравнить
+	f, err := os.CreateTemp("", name+"-*.png")
+	if err == nil {
+		f.Write(got)
+		f.Close()
+		t.Errorf("%s: изображение отличается от эталона, получено %s", path, f.Name())
+		return
+	}
+	t.Errorf("%s: изображение отличается от эталона", path)
+}
+
+// любое изменение отрисовки видно как несовпадение с эталоном;
+// если изменение задумано, эталоны перезаписывает go test -update
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		name  string
+		shape string
+		opts  RenderOptions
+	}{
+		{"single", single, RenderOptions{Size: 200}},
+		{"hexagon-fill", hexagon, RenderOptions{Size: 240, Fill: true, UpColor: "#e8a33d", DownColor: "#3d7be8"}},
+		{"chiral5-index", chiral5, RenderOptions{Size: 240, ShowIndex: true}},
+		{"strip4-outline", strip4, RenderOptions{Size: 240, OutlineOnly: true}},
+	}
+	for i := 0; i < len(cases); i++ {
+		checkGolden(t, cases[i].name, mustParse(t, cases[i].shape).getCentered(), cases[i].opts)
+	}
+}
+
+// при нечётном размахе фигура всё равно рисуется точно по центру
+func TestGoldenOddExtent(t *testing.T) {
+	checkGolden(t, "odd-extent", mustParse(t, strip3).getCentered(), RenderOptions{Size: 240})
+}
+
+// все положения фигур из n треугольников, сдвинутые по-разному
+func placements(n int) []*Pattern {
+	var variants []*Pattern
+	var result []*Pattern
+	ps := generated(n)
+	for i := 0; i < len(ps); i++ {
+		variants = ps[i].allVariants()
+		for j := 0; j < len(variants); j++ {
+			result = append(result, variants[j].getShifted(j-i, 1+(i+j)%3))
+		}
+	}
+	return result
+}
+
+func TestCanonicalMatchesIsEqual(t *testing.T) {
+	var ps []*Pattern
+	var canonical []string
+	for n := 5; n <= 6; n++ {
+		ps = placements(n)
+		canonical = make([]string, len(ps))
+		for i := 0; i < len(ps); i++ {
+			canonical[i] = ps[i].getCanonical()
+		}
+		for i := 0; i < len(ps); i++ {
+			for j := 0; j < len(ps); j++ {
+				if (canonical[i] == canonical[j]) != ps[i].isEqual(ps[j]) {
+					t.Errorf("%s и %s: совпадение канонических форм %v, isEqual %v", ps[i].Encode(), ps[j].Encode(), canonical[i] == canonical[j], ps[i].isEqual(ps[j]))
+				}
+			}
+		}
+	}
+}
+
+// сравнение с повёрнутой и отражённой копией, как при отборе повторов
+func BenchmarkIsEqual(b *testing.B) {
+	ps := generated(12)
+	others := make([]*Pattern, len(ps))
+	for i := 0; i < len(ps); i++ {
+		others[i] = ps[i].getRotated(2).getReflected(1)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !ps[i%len(ps)].isEqual(others[i%len(ps)]) {
+			b.Fatalf("фигура %d не совпала со своей копией", i%len(ps))
+		}
+	}
+}
+
+// положения фигур из 13 треугольников: каноническое среди них в среднем
+// одно из двенадцати; полная каноническая форма - все 36 выравниваний
+func BenchmarkGetCanonical(b *testing.B) {
+	ps := placements(13)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps[i%len(ps)].getCanonical()
+	}
+}
+
+// отбор при переборе: выравнивания прекращаются на первом меньшем
+func BenchmarkCanonicalIfPlaced(b *testing.B) {
+	ps := placements(13)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps[i%len(ps)].canonicalIfPlaced()
+	}
+}
+
+// перебор от затравки идёт через корзины сигнатур: canonicals/op
+// показывает, сколько канонических форм они не дали вычислить;
+// go test -bench SignatureBuckets/13 -benchtime 1x
+func BenchmarkSignatureBuckets(b *testing.B) {
+	seed := mustParse(b, single)
+	for n := 9; n <= 13; n++ {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pc := NewCollection()
+				pc.generatePatterns(n-1, seed)
+				if len(pc.patterns) != freeCounts[n-1] {
+					b.Fatalf("фигур %d, ожидалось %d", len(pc.patterns), freeCounts[n-1])
+				}
+				b.ReportMetric(float64(pc.stats.nodes), "nodes/op")
+				b.ReportMetric(float64(pc.stats.canonicals), "canonicals/op")
+			}
+		})
+	}
+}
+
+// перебор фигур из 10-14 треугольников, например
+// go test -bench GeneratePatterns/14 -cpuprofile cpu.out
+func BenchmarkGeneratePatterns(b *testing.B) {
+	for n := 10; n <= 14; n++ {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pc := NewCollection()
+				pc.generatePatterns(n, NewPattern())
+				if len(pc.patterns) != freeCounts[n-1] {
+					b.Fatalf("фигур %d, ожидалось %d", len(pc.patterns), freeCounts[n-1])
+				}
+			}
+		})
+	}
+}
+
+// отрисовка без кодирования PNG
+func BenchmarkDrawPattern(b *testing.B) {
+	var pimg patternImage
+	ps := generated(10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pimg = newPatternImage(RenderOptions{Size: 400, Fill: true})
+		pimg.drawPattern(ps[i%len(ps)])
+	}
+}
@@ -0,0 +1,213 @@
+package polyiamond
+
+// Перебор Редельмейера для треугольной сетки: каждая фиксированная фигура
+// (с точностью до сдвига) строится ровно один раз, как множество клеток
+// не меньше корня в порядке isLess, содержащее корень. Порядок isLess
+// не меняется при сдвигах, поэтому наименьший треугольник фигуры всегда
+// можно совместить с корнем того же направления; корней два - "верхний"
+// и "нижний". Из фиксированных фигур остаются только те, что совпадают
+// со своей канонической формой, так что каждая фигура находится один раз
+// без общего набора уже найденных.
+
+var redelmeierRoots = []Triangle{{0, 1, 0}, {0, 0, -1}}
+
+// клетка, ожидающая добавления: соседка клетки parent по оси axis
+type untriedCell struct {
+	t      Triangle
+	parent int
+	axis   int
+}
+
+// состояние перебора: текущая фигура в порядке добавления
+// и все клетки, уже попадавшие в очередь
+type redelmeierSearch struct {
+	root    Triangle
+	cells   []Triangle
+	steps   []buildStep
+	reached map[Triangle]bool
+}
+
+func (s *redelmeierSearch) getCopy() *redelmeierSearch {
+	sCopy := &redelmeierSearch{
+		root:    s.root,
+		cells:   make([]Triangle, len(s.cells), cap(s.cells)),
+		steps:   make([]buildStep, len(s.steps), cap(s.steps)),
+		reached: make(map[Triangle]bool, len(s.reached)),
+	}
+	copy(sCopy.cells, s.cells)
+	copy(sCopy.steps, s.steps)
+	for t := range s.reached {
+		sCopy.reached[t] = true
+	}
+	return sCopy
+}
+
+func (pc *Collection) generateRedelmeier(numTriangles int) {
+	for i := 0; i < len(redelmeierRoots) && (pc.ctx == nil || pc.ctx.Err() == nil); i++ {
+		root := redelmeierRoots[i]
+		s := &redelmeierSearch{
+			root:    root,
+			cells:   make([]Triangle, 0, numTriangles),
+			steps:   make([]buildStep, 0, numTriangles),
+			reached: map[Triangle]bool{root: true},
+		}
+		pc.extendRedelmeier(s, []untriedCell{{t: root, parent: -1}}, numTriangles)
+	}
+}
+
+func (pc *Collection) extendRedelmeier(s *redelmeierSearch, untried []untriedCell, toAdd int) {
+	var c untriedCell
+	var neighbour *Triangle
+	var next []untriedCell
+	var added int
+	for len(untried) > 0 {
+		if pc.ctx != nil && pc.ctx.Err() != nil {
+			return
+		}
+		c = untried[len(untried)-1]
+		untried = untried[:len(untried)-1]
+		s.cells = append(s.cells, c.t)
+		if c.parent >= 0 {
+			s.steps = append(s.steps, buildStep{index: c.parent, axis: c.axis})
+		}
+		pc.visitNode()
+		if toAdd == 1 {
+			pc.acceptFixed(s)
+		} else {
+			next = make([]untriedCell, len(untried), len(untried)+3)
+			copy(next, untried)
+			added = 0
+			for axis := 1; axis <= 3; axis++ {
+				neighbour = c.t.getNeighbour(axis)
+				if s.reached[*neighbour] || neighbour.isLess(&s.root) {
+					continue
+				}
+				s.reached[*neighbour] = true
+				next = append(next, untriedCell{t: *neighbour, parent: len(s.cells) - 1, axis: axis})
+				added++
+			}
+			if (pc.tasks != nil || pc.onTask != nil) && len(s.cells) >= pc.splitAt {
+				pc.sendRedelmeierTask(s, next, toAdd-1)
+			} else {
+				pc.extendRedelmeier(s, next, toAdd-1)
+			}
+			for i := len(next) - added; i < len(next); i++ {
+				delete(s.reached, next[i].t)
+			}
+		}
+		s.cells = s.cells[:len(s.cells)-1]
+		if c.parent >= 0 {
+			s.steps = s.steps[:len(s.steps)-1]
+		}
+	}
+}
+
+func (pc *Collection) sendRedelmeierTask(s *redelmeierSearch, untried []untriedCell, toAdd int) {
+	if pc.onTask != nil {
+		pc.onTask(splitTask{index: pc.numTasks, toAdd: toAdd, search: s, untried: untried})
+		pc.numTasks++
+		return
+	}
+	task := splitTask{index: pc.numTasks, toAdd: toAdd, search: s.getCopy(), untried: untried}
+	select {
+	case pc.tasks <- task:
+		pc.numTasks++
+	case <-pc.ctx.Done():
+	}
+}
+
+// сдвиг фигуры, совмещающий её наименьший треугольник с корнем перебора
+// того же направления: так же расположены фигуры, найденные перебором
+func (p *Pattern) getRooted() *Pattern {
+	first := p.getSortedTriangles()[0]
+	root := redelmeierRoots[0]
+	if !first.isUpward() {
+		root = redelmeierRoots[1]
+	}
+	dx, dy := root.x-first.x, root.y-first.y
+	return p.getShifted(dx, 3).getShifted(dx+dy, 1)
+}
+
+// фигура остаётся, если она - сдвиг своей канонической формы
+func (pc *Collection) acceptFixed(s *redelmeierSearch) {
+	p := newPatternWithCap(len(s.cells))
+	for i := 0; i < len(s.cells); i++ {
+		p.addTriangle(newTriangle(s.cells[i].x, s.cells[i].y, s.cells[i].z))
+	}
+	if pc.onFixed != nil {
+		pc.onFixed(p)
+		return
+	}
+	canonical, ok := p.canonicalIfPlaced()
+	if !ok || (pc.known != nil && pc.known[canonical]) {
+		return
+	}
+	if pc.recordSteps {
+		p.buildSteps = make([]buildStep, len(s.steps))
+		copy(p.buildSteps, s.steps)
+		p.buildRoot = s.root
+	}
+	pc.appendFound(p, canonical)
+}
+
+// каноническая форма p, если p совпадает с ней с точностью до сдвига;
+// перебор положений прекращается на первом, которое меньше положения p
+func (p *Pattern) canonicalIfPlaced() (string, bool) {
+	var rotated, aligned *Pattern
+	var own string
+	for freeAxis := 1; freeAxis <= 3; freeAxis++ {
+		aligned = p.getAligned(freeAxis)
+		aligned.validateHash()
+		if own == "" || aligned.patternHash < own {
+			own = aligned.patternHash
+		}
+	}
+	rotated = p
+	for i := 1; i <= 6; i++ {
+		for j := 1; j <= 2; j++ {
+			if i == 1 && j == 1 {
+				continue
+			}
+			for freeAxis := 1; freeAxis <= 3; freeAxis++ {
+				if j == 1 {
+					aligned = rotated.getAligned(freeAxis)
+				} else {
+					aligned = rotated.getReflected(freeAxis).getAligned(freeAxis)
+				}
+				aligned.validateHash()
+				if aligned.patternHash < own {
+					return "", false
+				}
+			}
+		}
+		if i < 6 {
+			rotated = rotated.getRotated(1)
+		}
+	}
+	return own, true
+}
+
+// добавляет фигуру, о которой известно, что она найдена впервые
+func (pc *Collection) appendFound(p *Pattern, canonical string) {
+	centered := p.getCentered()
+	centered.copyBuild(p)
+	centered.normalizeOrder()
+	if pc.stream != nil {
+		pc.stats.accepted++
+		select {
+		case pc.stream <- centered:
+		case <-pc.ctx.Done():
+		}
+		return
+	}
+	centered.buildIndex()
+	if pc.shared != nil {
+		pc.seq++
+		pc.shared.mu.Lock()
+		pc.shared.entries[canonical] = &sharedEntry{task: pc.task, seq: pc.seq, p: centered}
+		pc.shared.mu.Unlock()
+		return
+	}
+	pc.patterns = append(pc.patterns, centered)
+	pc.stats.accepted++
+}
@@ -0,0 +1,107 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+)
+
+// Фигура - rep-k, если k её копий покрывают ту же фигуру, увеличенную
+// в √k раз. Проверяются только целые увеличения m, то есть k = m²:
+// увеличенная фигура строится на той же сетке, а её покрытие ищется
+// тем же перебором, что и для -tile.
+
+type RepTile struct {
+	// номер фигуры в коллекции и её краткая запись
+	Index int
+	Code  string
+	// числа копий k, при которых фигура покрывает свою увеличенную копию
+	Orders []int
+}
+
+// фигура, увеличенная в scale раз относительно начала координат - вершины
+// сетки: треугольник сетки входит в неё, если его центр, уменьшенный
+// в scale раз, лежит в одном из треугольников фигуры
+func (p *Pattern) getScaled(scale int) (*Pattern, error) {
+	var t *Triangle
+	var cx, cy float64
+	var err error
+	scaled := NewPattern()
+	for i := 0; i < len(p.triangles); i++ {
+		v := p.triangles[i].vertices()
+		for x := scale * (p.triangles[i].x - 1); x <= scale*(p.triangles[i].x+1); x++ {
+			for y := scale * (p.triangles[i].y - 1); y <= scale*(p.triangles[i].y+1); y++ {
+				for _, z := range []int{1 - x - y, -1 - x - y} {
+					t, err = NewTriangle(x, y, z)
+					if err != nil {
+						return nil, err
+					}
+					cx, cy = t.getCenter()
+					if isInsideTriangle(cx/float64(scale), cy/float64(scale), v) {
+						scaled.addTriangle(t)
+					}
+				}
+			}
+		}
+	}
+	if scaled.Len() != scale*scale*p.Len() {
+		return nil, fmt.Errorf("увеличенная в %d раз фигура содержит %d треугольников вместо %d",
+			scale, scaled.Len(), scale*scale*p.Len())
+	}
+	return scaled, nil
+}
+
+// точка строго внутри треугольника: по одну сторону от всех его сторон
+func isInsideTriangle(x, y float64, v [3][2]float64) bool {
+	var d [3]float64
+	for i := 0; i < 3; i++ {
+		j := (i + 1) % 3
+		d[i] = (v[j][0]-v[i][0])*(y-v[i][1]) - (v[j][1]-v[i][1])*(x-v[i][0])
+	}
+	return (d[0] > 0 && d[1] > 0 && d[2] > 0) || (d[0] < 0 && d[1] < 0 && d[2] < 0)
+}
+
+// RepTileOrders возвращает числа копий k ≤ maxOrder, при которых фигура
+// покрывает свою увеличенную копию; при отмене ctx список неполный
+func (p *Pattern) RepTileOrders(ctx context.Context, maxOrder int) ([]int, error) {
+	var orders []int
+	for scale := 2; scale*scale <= maxOrder && ctx.Err() == nil; scale++ {
+		region, err := p.getScaled(scale)
+		if err != nil {
+			return orders, err
+		}
+		if len(SolveTilings(ctx, region, []*Pattern{p}, true, 1)) > 0 {
+			orders = append(orders, scale*scale)
+		}
+	}
+	return orders, nil
+}
+
+// FindRepTiles проверяет фигуры коллекции в jobs потоков и возвращает
+// rep-tiles с числом копий не больше maxOrder в порядке номеров
+func FindRepTiles(ctx context.Context, pc *Collection, maxOrder, jobs int) ([]RepTile, error) {
+	var reptiles []RepTile
+	found := make([][]int, len(pc.patterns))
+	errs := forEachParallel(len(pc.patterns), jobs, func(i int) error {
+		var err error
+		if ctx.Err() != nil {
+			return nil
+		}
+		found[i], err = pc.patterns[i].RepTileOrders(ctx, maxOrder)
+		if err != nil {
+			return fmt.Errorf("фигура %d: %v", i, err)
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(found); i++ {
+		if len(found[i]) > 0 {
+			reptiles = append(reptiles, RepTile{Index: i, Code: pc.patterns[i].Encode(), Orders: found[i]})
+		}
+	}
+	return reptiles, nil
+}
@@ -0,0 +1,142 @@
+package polyiamond
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+)
+
+// Случайная фигура строится ростом кластера, как в задаче о перколяции:
+// начальный треугольник - "верхний" или "нижний" с вероятностью 1/2,
+// а каждый ещё не рассмотренный сосед кластера с вероятностью 1/2 входит
+// в него или остаётся пустым. Положение фигуры из n треугольников с t
+// пустыми соседями получается с вероятностью n/2·2^-(n-1+t); у фигуры
+// не больше n+2 соседей, и после приёма с вероятностью 2^-(n+2-t) все
+// положения равновероятны. Фигура с s симметриями встречается в 12/s
+// положениях, поэтому она принимается ещё с вероятностью s/12.
+
+// через столько попыток поток добавляет их к общему счётчику
+const sampleReportInterval = 1 << 12
+
+// SampleRange для каждого размера от minTriangles до maxTriangles выбирает
+// count фигур, равновероятных среди всех фигур этого размера с точностью
+// до поворотов, отражений и сдвигов. Фигуры выбираются независимо и могут
+// повторяться; выборка определяется seed и не зависит от числа потоков.
+// При отмене ctx последняя коллекция неполная.
+func SampleRange(ctx context.Context, minTriangles, maxTriangles, count int, seed uint64, workers int, onProgress func(numTriangles, nodes, accepted int)) []*Collection {
+	collections := make([]*Collection, 0, maxTriangles-minTriangles+1)
+	for n := minTriangles; n <= maxTriangles; n++ {
+		collections = append(collections, sampleSize(ctx, n, count, seed, workers, onProgress))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return collections
+}
+
+func sampleSize(ctx context.Context, numTriangles, count int, seed uint64, workers int, onProgress func(numTriangles, nodes, accepted int)) *Collection {
+	var mu sync.Mutex
+	nodes, accepted, reported := 0, 0, 0
+	samples := make([]*Pattern, count)
+	forEachParallel(count, workers, func(i int) error {
+		var p *Pattern
+		// у каждой фигуры свой генератор, поэтому она не зависит
+		// от того, какой поток и после каких фигур её выбирает
+		rng := rand.New(rand.NewPCG(seed, uint64(numTriangles)<<32|uint64(i)))
+		decided := make(map[Triangle]bool, 4*numTriangles)
+		pending := 0
+		for p == nil {
+			p = growRandom(rng, numTriangles, decided)
+			pending++
+			if p == nil && pending < sampleReportInterval {
+				continue
+			}
+			mu.Lock()
+			nodes += pending
+			if p != nil {
+				accepted++
+			}
+			if onProgress != nil && (nodes-reported >= progressInterval || accepted == count) {
+				reported = nodes
+				onProgress(numTriangles, nodes, accepted)
+			}
+			mu.Unlock()
+			pending = 0
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+		samples[i] = canonicalPattern(p)
+		return nil
+	})
+	pc := NewCollection()
+	pc.stats.nodes = nodes
+	for i := 0; i < len(samples); i++ {
+		if samples[i] != nil {
+			pc.patterns = append(pc.patterns, samples[i])
+			pc.stats.accepted++
+		}
+	}
+	return pc
+}
+
+// одна попытка выбора: фигура из n треугольников или nil, если кластер
+// получился другого размера или отвергнут; decided - рабочий набор
+// рассмотренных треугольников, общий для попыток одного потока
+func growRandom(rng *rand.Rand, n int, decided map[Triangle]bool) *Pattern {
+	var neighbour *Triangle
+	empty := 0
+	clear(decided)
+	start := newTriangle(0, 1, 0)
+	if rng.IntN(2) == 1 {
+		start = newTriangle(0, -1, 0)
+	}
+	p := newPatternWithCap(n)
+	p.addTriangle(start)
+	decided[*start] = true
+	for i := 0; i < p.Len(); i++ {
+		for axis := 1; axis <= 3; axis++ {
+			neighbour = p.triangles[i].getNeighbour(axis)
+			if decided[*neighbour] {
+				continue
+			}
+			decided[*neighbour] = true
+			if rng.IntN(2) == 0 {
+				empty++
+				continue
+			}
+			if p.Len() == n {
+				return nil
+			}
+			p.addTriangle(neighbour)
+		}
+	}
+	if p.Len() < n {
+		return nil
+	}
+	for i := empty; i < n+2; i++ {
+		if rng.IntN(2) == 0 {
+			return nil
+		}
+	}
+	rotations, reflections := p.symmetryCounts()
+	if rng.IntN(12) >= rotations+reflections {
+		return nil
+	}
+	return p
+}
+
+// фигура в канонической форме и в том же положении, что и при полном
+// переборе: наименьший треугольник совмещён с корнем перебора Редельмейера
+func canonicalPattern(p *Pattern) *Pattern {
+	canonical := p.getCanonical()
+	aligned := newPatternWithCap(p.Len())
+	for i := 0; i+8 <= len(canonical); i += 8 {
+		aligned.addTriangle(unpackTriangle(binary.BigEndian.Uint64([]byte(canonical[i : i+8]))))
+	}
+	centered := aligned.getRooted().getCentered()
+	centered.normalizeOrder()
+	centered.buildIndex()
+	return centered
+}
@@ -0,0 +1,305 @@
+package polyiamond
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// наибольший масштаб, который можно запросить через ?scale=
+const maxServerScale = 1000
+
+// наибольшее число изображений в кэше; при переполнении
+// вытесняются сохранённые раньше всех
+const maxCachedImages = 1024
+
+// ответ на запрос фигур большого размера ждёт окончания их генерации
+const serverWriteTimeout = 10 * time.Minute
+
+type patternServer struct {
+	opts        RenderOptions
+	collections map[int]*generation
+	images      map[imageKey][]byte
+	imageOrder  []imageKey
+	mu          sync.Mutex
+}
+
+// генерация фигур одного размера: запросы, пришедшие во время неё, ждут
+// её окончания, а не запускают свою. Генерация отменяется, когда
+// не остаётся ни одного ждущего запроса.
+type generation struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	pc      *Collection
+}
+
+// готовые изображения кэшируются вместе с параметрами отрисовки
+type imageKey struct {
+	numTriangles int
+	index        int
+	format       string
+	scale        float64
+}
+
+func newPatternServer(opts RenderOptions) *patternServer {
+	return &patternServer{
+		opts:        opts,
+		collections: make(map[int]*generation),
+		images:      make(map[imageKey][]byte),
+	}
+}
+
+// фигуры из numTriangles треугольников; ошибка, если запрос отменён
+// раньше, чем они готовы
+func (ps *patternServer) getCollection(ctx context.Context, numTriangles int) (*Collection, error) {
+	ps.mu.Lock()
+	g, ok := ps.collections[numTriangles]
+	if !ok {
+		genCtx, cancel := context.WithCancel(context.Background())
+		g = &generation{done: make(chan struct{}), cancel: cancel}
+		ps.collections[numTriangles] = g
+		go ps.generate(genCtx, numTriangles, g)
+	}
+	g.waiters++
+	ps.mu.Unlock()
+	select {
+	case <-g.done:
+	case <-ctx.Done():
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	g.waiters--
+	if g.pc != nil {
+		return g.pc, nil
+	}
+	if g.waiters == 0 {
+		g.cancel()
+		if ps.collections[numTriangles] == g {
+			delete(ps.collections, numTriangles)
+		}
+	}
+	return nil, ctx.Err()
+}
+
+func (ps *patternServer) generate(ctx context.Context, numTriangles int, g *generation) {
+	// номера фигур совпадают с номерами файлов при генерации из командной строки
+	pc := GenerateRange(ctx, numTriangles, numTriangles, NewPattern(), false, false, nil, runtime.NumCPU(), "", nil, nil)[0]
+	ps.mu.Lock()
+	if ctx.Err() == nil {
+		g.pc = pc
+	}
+	ps.mu.Unlock()
+	g.cancel()
+	close(g.done)
+}
+
+func (ps *patternServer) cacheImage(key imageKey, data []byte) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, ok := ps.images[key]; ok {
+		return
+	}
+	if len(ps.imageOrder) >= maxCachedImages {
+		delete(ps.images, ps.imageOrder[0])
+		ps.imageOrder = ps.imageOrder[1:]
+	}
+	ps.images[key] = data
+	ps.imageOrder = append(ps.imageOrder, key)
+}
+
+func (ps *patternServer) writePNG(w http.ResponseWriter, p *Pattern) {
+	w.Header().Set("Content-Type", "image/png")
+	err := WritePattern(w, p, ps.opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// net/url отбрасывает параметры, содержащие ';', поэтому
+// список координат разбирается из строки запроса вручную
+func rawQueryValue(rawQuery, key string) (string, error) {
+	params := strings.Split(rawQuery, "&")
+	for i := 0; i < len(params); i++ {
+		name, value, _ := strings.Cut(params[i], "=")
+		if name == key {
+			return url.QueryUnescape(value)
+		}
+	}
+	return "", nil
+}
+
+func (ps *patternServer) handlePattern(w http.ResponseWriter, r *http.Request) {
+	coords, err := rawQueryValue(r.URL.RawQuery, "coords")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := ParsePattern(strings.ReplaceAll(coords, ";", " "))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Len() > MaxNumTriangles {
+		http.Error(w, fmt.Sprintf("фигура должна содержать не больше %d треугольников", MaxNumTriangles), http.StatusBadRequest)
+		return
+	}
+	if err = validatePattern(p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// размер холста растёт с размахом фигуры
+	x1, y1, x2, y2 := p.cartesianBounds()
+	if x2-x1 > MaxNumTriangles || y2-y1 > MaxNumTriangles {
+		http.Error(w, "фигура слишком велика", http.StatusBadRequest)
+		return
+	}
+	ps.writePNG(w, p.getCentered())
+}
+
+func parseNumTriangles(s string) (int, error) {
+	numTriangles, err := strconv.Atoi(s)
+	if err != nil || numTriangles < MinNumTriangles || numTriangles > MaxNumTriangles {
+		return 0, fmt.Errorf("n должно быть от %d до %d", MinNumTriangles, MaxNumTriangles)
+	}
+	return numTriangles, nil
+}
+
+func (ps *patternServer) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	numTriangles, err := parseNumTriangles(r.URL.Query().Get("n"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("i"))
+	if err != nil {
+		http.Error(w, "неверный номер фигуры", http.StatusBadRequest)
+		return
+	}
+	pc, err := ps.getCollection(r.Context(), numTriangles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if index < 0 || index >= len(pc.patterns) {
+		http.Error(w, fmt.Sprintf("i должно быть от 0 до %d", len(pc.patterns)-1), http.StatusBadRequest)
+		return
+	}
+	ps.writePNG(w, pc.patterns[index])
+}
+
+// GET /patterns/{n}: координаты и свойства всех фигур из n треугольников
+func (ps *patternServer) handlePatternList(w http.ResponseWriter, r *http.Request) {
+	numTriangles, err := parseNumTriangles(r.PathValue("n"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	pc, err := ps.getCollection(r.Context(), numTriangles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(pc.jsonPatterns())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GET /patterns/{n}/{i}.png или .svg, масштаб задаётся параметром scale
+func (ps *patternServer) handlePatternImage(w http.ResponseWriter, r *http.Request) {
+	var key imageKey
+	var err error
+	key.numTriangles, err = parseNumTriangles(r.PathValue("n"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	name, format, _ := strings.Cut(r.PathValue("file"), ".")
+	if format != "png" && format != "svg" {
+		http.Error(w, "поддерживаются только .png и .svg", http.StatusNotFound)
+		return
+	}
+	key.format = format
+	key.index, err = strconv.Atoi(name)
+	if err != nil {
+		http.Error(w, "неверный номер фигуры", http.StatusNotFound)
+		return
+	}
+	if s := r.URL.Query().Get("scale"); s != "" {
+		key.scale, err = strconv.ParseFloat(s, 64)
+		if err != nil || key.scale <= 0 || key.scale > maxServerScale {
+			http.Error(w, fmt.Sprintf("scale должен быть больше 0 и не больше %d", maxServerScale), http.StatusBadRequest)
+			return
+		}
+	}
+	pc, err := ps.getCollection(r.Context(), key.numTriangles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if key.index < 0 || key.index >= len(pc.patterns) {
+		http.Error(w, fmt.Sprintf("номер фигуры должен быть от 0 до %d", len(pc.patterns)-1), http.StatusNotFound)
+		return
+	}
+
+	ps.mu.Lock()
+	data, ok := ps.images[key]
+	ps.mu.Unlock()
+	if !ok {
+		data, err = ps.render(pc.patterns[key.index], key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ps.cacheImage(key, data)
+	}
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		w.Header().Set("Content-Type", "image/png")
+	}
+	w.Write(data)
+}
+
+func (ps *patternServer) render(p *Pattern, key imageKey) ([]byte, error) {
+	var buf bytes.Buffer
+	opts := ps.opts
+	if key.scale > 0 {
+		opts.Scale = key.scale
+		opts.Size = 0
+	}
+	if key.format == "svg" {
+		if err := WritePatternSVG(&buf, p, opts); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return renderToBytes(p, opts)
+}
+
+func Serve(addr string, opts RenderOptions) error {
+	ps := newPatternServer(opts)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pattern", ps.handlePattern)
+	mux.HandleFunc("GET /generate", ps.handleGenerate)
+	mux.HandleFunc("GET /patterns/{n}", ps.handlePatternList)
+	mux.HandleFunc("GET /patterns/{n}/{file}", ps.handlePatternImage)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      serverWriteTimeout,
+		IdleTimeout:       time.Minute,
+	}
+	return server.ListenAndServe()
+}
@@ -0,0 +1,30 @@
+package polyiamond
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlePattern(t *testing.T) {
+	cases := []struct {
+		coords string
+		status int
+	}{
+		{"0,1,0;0,0,-1", http.StatusOK},
+		{"", http.StatusBadRequest},
+		{"0,1,2", http.StatusBadRequest},
+		// каждая координата допустима, но фигура не связна и огромна
+		{"0,1,0;1048575,-1048575,1", http.StatusBadRequest},
+		{"0,1,0;1,0,0", http.StatusBadRequest},
+		{"0,1,0;0,0,-1;0,0,1;0,-1,0;-1,0,0;1,0,0;1,1,-1;1,-1,-1;-1,1,-1;-1,-1,1;1,0,-2;0,1,-2;-2,1,0;-2,0,1;2,-1,0;2,-2,1;-1,2,0", http.StatusBadRequest},
+	}
+	ps := newPatternServer(RenderOptions{Size: 100})
+	for i := 0; i < len(cases); i++ {
+		w := httptest.NewRecorder()
+		ps.handlePattern(w, httptest.NewRequest(http.MethodGet, "/pattern?coords="+cases[i].coords, nil))
+		if w.Code != cases[i].status {
+			t.Errorf("%q: ответ %d, ожидался %d: %s", cases[i].coords, w.Code, cases[i].status, w.Body.String())
+		}
+	}
+}
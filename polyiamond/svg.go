@@ -0,0 +1,73 @@
+package polyiamond
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// В SVG рисуется только сама фигура: заливка и стороны треугольников,
+// без сетки, осей и подписей, чтобы файл можно было сразу отдать
+// в программу для резки или печати.
+func WritePatternSVG(w io.Writer, p *Pattern, opts RenderOptions) error {
+	var sb strings.Builder
+	var x, y, x1, y1, x2, y2, width float64
+	var v [3][2]float64
+	var color string
+	pimg := newPatternImage(opts)
+	pimg.setView(p)
+	linecap := "round"
+	if opts.SharpCorners {
+		linecap = "butt"
+	}
+
+	fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		int(pimg.width), int(pimg.height), int(pimg.width), int(pimg.height))
+	if !opts.Transparent {
+		fmt.Fprintf(&sb, "<rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n", opts.theme().background)
+	}
+
+	if opts.isFilled() {
+		for i := 0; i < len(p.triangles); i++ {
+			color = opts.fillColor(p, i)
+			v = p.triangles[i].vertices()
+			sb.WriteString("<polygon points=\"")
+			for j := 0; j < len(v); j++ {
+				x, y = pimg.toReal(v[j][0], v[j][1])
+				fmt.Fprintf(&sb, "%.2f,%.2f ", x, y)
+			}
+			fmt.Fprintf(&sb, "\" fill=\"%s\"/>\n", color)
+		}
+	}
+
+	_, edgeWidth, boundaryWidth := opts.lineWidths()
+	lines := p.edgeLines()
+	edgeColor := opts.theme().edge
+	for i := 0; i < len(lines); i++ {
+		x1, y1 = pimg.toReal(lines[i].x1, lines[i].y1)
+		x2, y2 = pimg.toReal(lines[i].x2, lines[i].y2)
+		width = edgeWidth
+		if lines[i].bold {
+			width = boundaryWidth
+		}
+		fmt.Fprintf(&sb, "<line x1=\"%.2f\" y1=\"%.2f\" x2=\"%.2f\" y2=\"%.2f\" stroke=\"%s\" stroke-width=\"%g\" stroke-linecap=\"%s\"/>\n",
+			x1, y1, x2, y2, edgeColor, width, linecap)
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func saveAsSVG(p *Pattern, path string, opts RenderOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WritePatternSVG(f, p, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package polyiamond
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// цвета оформления изображений: фон, сетка, оси и подписи, стороны фигуры;
+// заливка фигуры задаётся отдельно цветами из RenderOptions
+type theme struct {
+	background string
+	grid       string
+	axes       string
+	edge       string
+}
+
+// "print" - светлая сетка, чтобы при печати выделялась фигура
+var themes = map[string]theme{
+	"light": {background: "#ffffff", grid: "#010101", axes: "#0a0a0a", edge: "#000000"},
+	"dark":  {background: "#1e1e1e", grid: "#6e6e6e", axes: "#9a9a9a", edge: "#f0f0f0"},
+	"print": {background: "#ffffff", grid: "#bdbdbd", axes: "#8c8c8c", edge: "#000000"},
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// тема из opts с заменой отдельных цветов, если они заданы
+func (opts RenderOptions) theme() theme {
+	t, ok := themes[opts.Theme]
+	if !ok {
+		t = themes["light"]
+	}
+	if opts.BackgroundColor != "" {
+		t.background = opts.BackgroundColor
+	}
+	if opts.GridColor != "" {
+		t.grid = opts.GridColor
+	}
+	if opts.AxesColor != "" {
+		t.axes = opts.AxesColor
+	}
+	if opts.EdgeColor != "" {
+		t.edge = opts.EdgeColor
+	}
+	return t
+}
+
+// CheckTheme проверяет название темы и цвета оформления в виде #rgb или #rrggbb
+func (opts RenderOptions) CheckTheme() error {
+	if _, ok := themes[opts.Theme]; opts.Theme != "" && !ok {
+		names := make([]string, 0, len(themes))
+		for name := range themes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("неизвестная тема %q, допустимы %s", opts.Theme, strings.Join(names, ", "))
+	}
+	colors := []string{opts.BackgroundColor, opts.GridColor, opts.AxesColor, opts.EdgeColor}
+	for i := 0; i < len(colors); i++ {
+		if colors[i] != "" && !hexColor.MatchString(colors[i]) {
+			return fmt.Errorf("неверный цвет %q, нужен вид #rrggbb", colors[i])
+		}
+	}
+	return nil
+}
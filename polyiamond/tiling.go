@@ -0,0 +1,293 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// положение фигуры с точностью до сдвига: смещения треугольников
+// относительно наименьшего из них
+type pieceVariant struct {
+	upward  bool
+	offsets [][3]int
+}
+
+type tilingSolver struct {
+	ctx       context.Context
+	cells     []Triangle
+	owner     map[Triangle]int
+	variants  [][]pieceVariant
+	used      []bool
+	reuse     bool
+	limit     int
+	placed    []*Pattern
+	solutions [][]*Pattern
+	// при distinct из покрытий одним набором фигур сохраняется только
+	// первое, а номера фигур каждого решения записываются в pieceSets
+	distinct  bool
+	seenSets  map[string]bool
+	pieceSets [][]int
+}
+
+// ParseRegion строит область по описанию вида "hexagon:2", "triangle:4"
+// или "parallelogram:2x3", где числа - длины сторон в треугольниках
+func ParseRegion(s string) (*Pattern, error) {
+	var allowed func(*Triangle) bool
+	var sides []int
+	var side int
+	var err error
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("неверное описание области %q", s)
+	}
+	for _, field := range strings.Split(parts[1], "x") {
+		side, err = strconv.Atoi(field)
+		if err != nil || side < 1 {
+			return nil, fmt.Errorf("неверная сторона области %q", parts[1])
+		}
+		sides = append(sides, side)
+	}
+	switch {
+	case parts[0] == "hexagon" && len(sides) == 1:
+		allowed = withinHexagon(sides[0])
+	case parts[0] == "triangle" && len(sides) == 1:
+		allowed = withinTriangle(sides[0])
+	case parts[0] == "parallelogram" && len(sides) == 2:
+		allowed = withinParallelogram(sides[0], sides[1])
+	case parts[0] == "hexagon" || parts[0] == "triangle" || parts[0] == "parallelogram":
+		return nil, fmt.Errorf("неверные стороны области %q", s)
+	default:
+		return nil, fmt.Errorf("неизвестная форма области %q", parts[0])
+	}
+	cells := regionCells(allowed)
+	if len(cells) >= maxRegionCells {
+		return nil, fmt.Errorf("область %q слишком большая", s)
+	}
+	region := NewPattern()
+	for i := 0; i < len(cells); i++ {
+		region.addTriangle(cells[i])
+	}
+	region.normalizeOrder()
+	return region, nil
+}
+
+func newPieceVariants(p *Pattern) []pieceVariant {
+	var sorted []Triangle
+	var v pieceVariant
+	var key string
+	all := p.allVariants()
+	variants := make([]pieceVariant, 0, len(all))
+	seen := make(map[string]bool, len(all))
+	for i := 0; i < len(all); i++ {
+		sorted = all[i].getSortedTriangles()
+		v = pieceVariant{upward: sorted[0].isUpward(), offsets: make([][3]int, len(sorted))}
+		for j := 0; j < len(sorted); j++ {
+			v.offsets[j] = [3]int{sorted[j].x - sorted[0].x, sorted[j].y - sorted[0].y, sorted[j].z - sorted[0].z}
+		}
+		key = fmt.Sprint(v.upward, v.offsets)
+		if !seen[key] {
+			seen[key] = true
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// SolveTilings ищет точные покрытия области фигурами pieces: каждая клетка
+// области занята ровно одной фигурой. Без reuse каждая фигура используется
+// не больше одного раза. Поиск останавливается после limit решений
+// (0 - без ограничения) или при отмене ctx.
+func SolveTilings(ctx context.Context, region *Pattern, pieces []*Pattern, reuse bool, limit int) [][]*Pattern {
+	variants := make([][]pieceVariant, len(pieces))
+	for i := 0; i < len(pieces); i++ {
+		variants[i] = newPieceVariants(pieces[i])
+	}
+	s := newTilingSolver(ctx, region, variants, reuse, limit)
+	s.search(0)
+	return s.solutions
+}
+
+func newTilingSolver(ctx context.Context, region *Pattern, variants [][]pieceVariant, reuse bool, limit int) *tilingSolver {
+	s := &tilingSolver{
+		ctx:      ctx,
+		cells:    make([]Triangle, region.Len()),
+		owner:    make(map[Triangle]int, region.Len()),
+		variants: variants,
+		used:     make([]bool, len(variants)),
+		reuse:    reuse,
+		limit:    limit,
+	}
+	copy(s.cells, region.triangles)
+	sort.Slice(s.cells, func(i, j int) bool {
+		return s.cells[i].isLess(&s.cells[j])
+	})
+	for i := 0; i < len(s.cells); i++ {
+		s.owner[s.cells[i]] = -1
+	}
+	return s
+}
+
+func (s *tilingSolver) done() bool {
+	return (s.limit > 0 && len(s.solutions) >= s.limit) || s.ctx.Err() != nil
+}
+
+// первая свободная клетка в порядке isLess может быть покрыта только
+// наименьшим треугольником фигуры, поэтому перебираются лишь такие положения
+func (s *tilingSolver) search(start int) {
+	var cell *Triangle
+	for start < len(s.cells) && s.owner[s.cells[start]] >= 0 {
+		start++
+	}
+	if start == len(s.cells) {
+		s.addSolution()
+		return
+	}
+	cell = &s.cells[start]
+	for i := 0; i < len(s.variants) && !s.done(); i++ {
+		if s.used[i] && !s.reuse {
+			continue
+		}
+		for j := 0; j < len(s.variants[i]) && !s.done(); j++ {
+			if !s.place(cell, i, s.variants[i][j]) {
+				continue
+			}
+			s.search(start + 1)
+			s.unplace(i)
+		}
+	}
+}
+
+// кладёт фигуру i в положении v, совмещая её наименьший треугольник
+// с клеткой cell; false, если фигура не помещается в свободные клетки
+func (s *tilingSolver) place(cell *Triangle, i int, v pieceVariant) bool {
+	var t Triangle
+	if v.upward != cell.isUpward() {
+		return false
+	}
+	for k := 0; k < len(v.offsets); k++ {
+		t = Triangle{cell.x + v.offsets[k][0], cell.y + v.offsets[k][1], cell.z + v.offsets[k][2]}
+		owner, ok := s.owner[t]
+		if !ok || owner >= 0 {
+			return false
+		}
+	}
+	piece := NewPattern()
+	for k := 0; k < len(v.offsets); k++ {
+		t = Triangle{cell.x + v.offsets[k][0], cell.y + v.offsets[k][1], cell.z + v.offsets[k][2]}
+		s.owner[t] = len(s.placed)
+		piece.addTriangle(newTriangle(t.x, t.y, t.z))
+	}
+	s.placed = append(s.placed, piece)
+	s.used[i] = true
+	return true
+}
+
+// убирает последнюю положенную фигуру i
+func (s *tilingSolver) unplace(i int) {
+	piece := s.placed[len(s.placed)-1]
+	s.used[i] = false
+	s.placed = s.placed[:len(s.placed)-1]
+	for k := 0; k < len(piece.triangles); k++ {
+		s.owner[piece.triangles[k]] = -1
+	}
+}
+
+func (s *tilingSolver) addSolution() {
+	var pieceSet []int
+	if s.distinct {
+		for i := 0; i < len(s.used); i++ {
+			if s.used[i] {
+				pieceSet = append(pieceSet, i)
+			}
+		}
+		key := fmt.Sprint(pieceSet)
+		if s.seenSets[key] {
+			return
+		}
+		s.seenSets[key] = true
+		s.pieceSets = append(s.pieceSets, pieceSet)
+	}
+	solution := make([]*Pattern, len(s.placed))
+	copy(solution, s.placed)
+	s.solutions = append(s.solutions, solution)
+}
+
+// каждая фигура покрытия закрашивается своим цветом палитры,
+// стороны между фигурами рисуются жирно
+func renderTiling(region *Pattern, pieces []*Pattern, opts RenderOptions) patternImage {
+	pimg := newPatternImage(opts)
+	pimg.drawBackground(pimg.setView(region))
+	for i := 0; i < len(pieces); i++ {
+		pimg.img.SetHexColor(paletteColor(i))
+		for j := 0; j < len(pieces[i].triangles); j++ {
+			pimg.fillTriangle(&pieces[i].triangles[j])
+		}
+	}
+	for i := 0; i < len(pieces); i++ {
+		pimg.drawLines(pieces[i].edgeLines())
+	}
+	return pimg
+}
+
+// LoadPieces возвращает фигуры для покрытия: все фигуры из spec
+// треугольников, если spec - число, иначе фигуры из файла
+func LoadPieces(ctx context.Context, spec string, workers int) ([]*Pattern, error) {
+	var pc *Collection
+	var err error
+	numTriangles, convErr := strconv.Atoi(spec)
+	if convErr == nil {
+		if numTriangles < MinNumTriangles || numTriangles > MaxNumTriangles {
+			return nil, fmt.Errorf("неверное количество треугольников %d", numTriangles)
+		}
+		pc = GenerateRange(ctx, numTriangles, numTriangles, NewPattern(), false, false, nil, workers, "", nil, nil)[0]
+		return pc.patterns, nil
+	}
+	pc, err = loadPatterns(spec)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(pc.patterns); i++ {
+		err = validatePattern(pc.patterns[i])
+		if err != nil {
+			return nil, fmt.Errorf("фигура %d: %v", i, err)
+		}
+	}
+	return pc.patterns, nil
+}
+
+// SaveTilings ищет покрытия области regionSpec фигурами piecesSpec
+// и сохраняет каждое в dir как tiling_<номер>.png; возвращает число решений
+func SaveTilings(ctx context.Context, regionSpec, piecesSpec string, reuse bool, limit int, dir string, workers int, opts RenderOptions) (int, error) {
+	region, err := ParseRegion(regionSpec)
+	if err != nil {
+		return 0, err
+	}
+	pieces, err := LoadPieces(ctx, piecesSpec, workers)
+	if err != nil {
+		return 0, err
+	}
+	if len(pieces) == 0 {
+		return 0, fmt.Errorf("нет фигур для покрытия")
+	}
+	solutions := SolveTilings(ctx, region, pieces, reuse, limit)
+	if dir == "" {
+		dir = "."
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(solutions); i++ {
+		pimg := renderTiling(region, solutions[i], opts)
+		err = pimg.saveAsPNG(filepath.Join(dir, fmt.Sprintf("tiling_%d.png", i)))
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(solutions), nil
+}
@@ -0,0 +1,62 @@
+package polyiamond
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSolveTilings(t *testing.T) {
+	cases := []struct {
+		region    string
+		pieces    []string
+		reuse     bool
+		solutions int
+	}{
+		// покрытия шестиугольника ромбами - по формуле Макмагона
+		{"hexagon:1", []string{diamond}, true, 2},
+		{"hexagon:2", []string{diamond}, true, 20},
+		{"parallelogram:1x1", []string{diamond}, true, 1},
+		{"hexagon:1", []string{single}, true, 1},
+		// в треугольнике верхних треугольников на один больше, чем нижних,
+		// а ромб закрывает по одному каждого вида
+		{"triangle:2", []string{diamond}, true, 0},
+		{"triangle:3", []string{diamond}, true, 0},
+		// площадь шестиугольника не делится на площадь фигуры
+		{"hexagon:1", []string{strip4}, true, 0},
+		// без reuse ромба хватает только на две клетки из шести
+		{"hexagon:1", []string{diamond}, false, 0},
+		{"hexagon:1", []string{diamond, diamond, diamond}, false, 2 * 6},
+	}
+	for i := 0; i < len(cases); i++ {
+		region, err := ParseRegion(cases[i].region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pieces := make([]*Pattern, len(cases[i].pieces))
+		for j := 0; j < len(pieces); j++ {
+			pieces[j] = mustParse(t, cases[i].pieces[j])
+		}
+		solutions := SolveTilings(context.Background(), region, pieces, cases[i].reuse, 0)
+		if len(solutions) != cases[i].solutions {
+			t.Errorf("%s, фигуры %q, reuse %v: покрытий %d, ожидалось %d", cases[i].region, cases[i].pieces, cases[i].reuse, len(solutions), cases[i].solutions)
+		}
+		for j := 0; j < len(solutions); j++ {
+			if !coversExactly(region, solutions[j]) {
+				t.Errorf("%s, фигуры %q: покрытие %d не совпадает с областью", cases[i].region, cases[i].pieces, j)
+			}
+		}
+	}
+}
+
+func coversExactly(region *Pattern, placed []*Pattern) bool {
+	covered := NewPattern()
+	for i := 0; i < len(placed); i++ {
+		for j := 0; j < placed[i].Len(); j++ {
+			if covered.contains(&placed[i].triangles[j]) || !region.contains(&placed[i].triangles[j]) {
+				return false
+			}
+			covered.addTriangle(&placed[i].triangles[j])
+		}
+	}
+	return covered.Len() == region.Len()
+}
@@ -0,0 +1,143 @@
+package polyiamond
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// фигуры предыдущего размера продолжаются пачками: внутри пачки
+// продолжения ищутся параллельно, а добавляются по порядку, поэтому
+// результат не зависит от числа потоков
+const upToBatchSize = 1024
+
+type extension struct {
+	p         *Pattern
+	canonical string
+}
+
+// фигуры на один треугольник больше p, без повторов. С recordSteps p
+// восстанавливается по шагам построения, чтобы продолжить их запись.
+// Треугольники обходятся в порядке isLess, который не зависит от сдвига,
+// поэтому первым находится одно и то же положение фигуры независимо
+// от того, записываются ли шаги, а перед сохранением оно сдвигается
+// на корень перебора. Ошибка - шаги построения p не повторяются от start.
+func extensions(p *Pattern, start *Pattern, recordSteps bool) ([]extension, error) {
+	var neighbour *Triangle
+	var newSketch *Pattern
+	var canonical string
+	var i int
+	var err error
+	base := p
+	if recordSteps {
+		base, err = replayBuild(start, p.buildSteps)
+		if err != nil {
+			return nil, fmt.Errorf("фигура %s: %w", p.Encode(), err)
+		}
+	}
+	order := make([]int, base.Len())
+	for k := 0; k < len(order); k++ {
+		order[k] = k
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return base.triangles[order[a]].isLess(&base.triangles[order[b]])
+	})
+	result := make([]extension, 0, 3*base.Len())
+	seen := make(map[string]bool, 3*base.Len())
+	for k := 0; k < len(order); k++ {
+		i = order[k]
+		for axis := 1; axis <= 3; axis++ {
+			neighbour = base.triangles[i].getNeighbour(axis)
+			if base.contains(neighbour) {
+				continue
+			}
+			newSketch = base.getCopy()
+			newSketch.addTriangle(neighbour)
+			canonical = newSketch.getCanonical()
+			if seen[canonical] {
+				continue
+			}
+			seen[canonical] = true
+			newSketch = newSketch.getRooted()
+			if recordSteps {
+				newSketch.buildSteps = append(p.buildSteps[:len(p.buildSteps):len(p.buildSteps)], buildStep{index: i, axis: axis})
+				newSketch.buildRoot = p.buildRoot
+			}
+			result = append(result, extension{p: newSketch, canonical: canonical})
+		}
+	}
+	return result, nil
+}
+
+// GenerateUpTo перебирает фигуры всех размеров от 1 до maxTriangles за один
+// проход: фигуры каждого размера получаются добавлением треугольника
+// к фигурам предыдущего, а не перебором заново с одного треугольника.
+// Любая фигура содержит треугольник, без которого она остаётся связной,
+// поэтому так находятся все фигуры. С recordSteps у фигур записываются
+// шаги построения. Возвращает коллекции размеров 1, 2, ...;
+// при отмене ctx или ошибке последняя коллекция неполная.
+func GenerateUpTo(ctx context.Context, maxTriangles int, recordSteps bool, workers int, onProgress func(numTriangles, nodes, accepted int)) ([]*Collection, error) {
+	var wg sync.WaitGroup
+	var prev []*Pattern
+	var results [][]extension
+	var errs []error
+	start := NewPattern()
+	start.addTriangle(newTriangle(0, 1, 0))
+	first := start.getCopy()
+	if recordSteps {
+		first.buildRoot = start.triangles[0]
+	}
+	collections := make([]*Collection, 0, maxTriangles)
+	pc := NewCollection()
+	pc.recordSteps = recordSteps
+	pc.visitNode()
+	pc.appendUnique(first)
+	collections = append(collections, pc)
+	for n := 2; n <= maxTriangles && ctx.Err() == nil; n++ {
+		prev = pc.patterns
+		pc = NewCollection()
+		pc.recordSteps = recordSteps
+		if onProgress != nil {
+			numTriangles := n
+			pc.onProgress = func(nodes, accepted int) {
+				onProgress(numTriangles, nodes, accepted)
+			}
+		}
+		for from := 0; from < len(prev) && ctx.Err() == nil; from += upToBatchSize {
+			batch := prev[from:min(from+upToBatchSize, len(prev))]
+			results = make([][]extension, len(batch))
+			errs = make([]error, len(batch))
+			indices := make(chan int)
+			for w := 0; w < max(workers, 1); w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range indices {
+						results[i], errs[i] = extensions(batch[i], start, recordSteps)
+					}
+				}()
+			}
+			for i := 0; i < len(batch); i++ {
+				indices <- i
+			}
+			close(indices)
+			wg.Wait()
+			for i := 0; i < len(errs); i++ {
+				if errs[i] != nil {
+					pc.sortCanonical()
+					return append(collections, pc), errs[i]
+				}
+			}
+			for i := 0; i < len(results); i++ {
+				for j := 0; j < len(results[i]); j++ {
+					pc.visitNode()
+					pc.appendCanonical(results[i][j].p, results[i][j].canonical)
+				}
+			}
+		}
+		pc.sortCanonical()
+		collections = append(collections, pc)
+	}
+	return collections, nil
+}